@@ -0,0 +1,59 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/chemikadze/gonomi/manifest/compat"
+	"os"
+)
+
+// exits with 1 on breaking changes so it can guard CI
+func runCompat(args []string) int {
+	flags := flag.NewFlagSet("compat", flag.ContinueOnError)
+	asJSON := flags.Bool("json", false, "print results as JSON")
+	all := flags.Bool("all", false, "print compatible changes too")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 2 {
+		fmt.Fprintln(os.Stderr, "Usage: gonomi compat [-json] [-all] old.yml new.yml")
+		return 2
+	}
+	old, err := readApplication(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	new, err := readApplication(flags.Arg(1))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	results := compat.Check(old, new)
+	if !*all {
+		breaking := []compat.Result{}
+		for _, r := range results {
+			if r.Breaking {
+				breaking = append(breaking, r)
+			}
+		}
+		results = breaking
+	}
+	if *asJSON {
+		out, err := json.MarshalIndent(results, "", "  ")
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+		fmt.Println(string(out))
+	} else {
+		for _, r := range results {
+			fmt.Println(r)
+		}
+	}
+	if compat.HasBreaking(results) {
+		return 1
+	}
+	return 0
+}
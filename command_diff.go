@@ -0,0 +1,49 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/chemikadze/gonomi/manifest/diff"
+	"os"
+)
+
+// exits with 1 when manifests differ, like diff(1)
+func runDiff(args []string) int {
+	flags := flag.NewFlagSet("diff", flag.ContinueOnError)
+	asJSON := flags.Bool("json", false, "print changes as JSON")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 2 {
+		fmt.Fprintln(os.Stderr, "Usage: gonomi diff [-json] old.yml new.yml")
+		return 2
+	}
+	old, err := readApplication(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	new, err := readApplication(flags.Arg(1))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	changes := diff.Compare(old, new)
+	if *asJSON {
+		out, err := json.MarshalIndent(changes, "", "  ")
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+		fmt.Println(string(out))
+	} else {
+		for _, change := range changes {
+			fmt.Println(change)
+		}
+	}
+	if len(changes) != 0 {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"github.com/chemikadze/gonomi/manifest/format"
+	"io/ioutil"
+	"os"
+)
+
+func runFmt(args []string) int {
+	flags := flag.NewFlagSet("fmt", flag.ContinueOnError)
+	write := flags.Bool("w", false, "write result to source file instead of stdout")
+	diff := flags.Bool("d", false, "display diffs instead of rewriting files")
+	list := flags.Bool("l", false, "list files whose formatting differs")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() == 0 {
+		if *write {
+			fmt.Fprintln(os.Stderr, "gonomi fmt: cannot use -w with standard input")
+			return 2
+		}
+		src, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		return fmtSource("<stdin>", src, false, *diff, *list)
+	}
+	status := 0
+	for _, path := range flags.Args() {
+		src, err := ioutil.ReadFile(path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			status = 1
+			continue
+		}
+		if s := fmtSource(path, src, *write, *diff, *list); s != 0 {
+			status = s
+		}
+	}
+	return status
+}
+
+func fmtSource(path string, src []byte, write, diff, list bool) int {
+	formatted, err := format.Format(src)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", path, err)
+		return 1
+	}
+	changed := !bytes.Equal(src, formatted)
+	if list && changed {
+		fmt.Println(path)
+	}
+	if diff && changed {
+		fmt.Print(unifiedDiff(path+".orig", path, string(src), string(formatted)))
+	}
+	if write && changed {
+		info, err := os.Stat(path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		if err := ioutil.WriteFile(path, formatted, info.Mode().Perm()); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	}
+	if !write && !diff && !list {
+		os.Stdout.Write(formatted)
+	}
+	return 0
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFmtKeepsMalformedFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gonomi-fmt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "app.yml")
+	src := "application:\n  components:\n    x:\n      interfaces:\n        i:\n          p: publish-signal(lst<string>)\n"
+	if err := ioutil.WriteFile(path, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if status := runFmt([]string{"-w", path}); status != 1 {
+		t.Errorf("Exit status 1 expected, got %d", status)
+	}
+	written, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(written) != src {
+		t.Errorf("File should not be rewritten, got:\n%s", written)
+	}
+}
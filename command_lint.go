@@ -0,0 +1,55 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/chemikadze/gonomi/manifest"
+	"github.com/chemikadze/gonomi/manifest/lint"
+	"io/ioutil"
+	"os"
+)
+
+// exits with 1 when anything is found
+func runLint(args []string) int {
+	flags := flag.NewFlagSet("lint", flag.ContinueOnError)
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "Usage: gonomi lint manifest.yml")
+		return 2
+	}
+	app, err := readApplication(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	src, err := ioutil.ReadFile(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	m, err := manifest.ParseManifest(string(src))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	// services and policies are checked against application with imports
+	m.Application = app
+	findings, err := lint.LintManifest(m)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	// lint:ignore comments of the manifest itself, imported files are not read
+	if suppressions, err := lint.ParseSuppressions(string(src)); err == nil {
+		findings = suppressions.Filter(findings)
+	}
+	for _, f := range findings {
+		fmt.Println(f)
+	}
+	if len(findings) != 0 {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,20 @@
+package main
+
+import (
+	"fmt"
+	"github.com/chemikadze/gonomi/lsp"
+	"os"
+)
+
+// language server for editors, talks LSP over stdin and stdout
+func runLSP(args []string) int {
+	if len(args) != 0 {
+		fmt.Fprintln(os.Stderr, "Usage: gonomi lsp")
+		return 2
+	}
+	if err := lsp.NewServer(os.Stdin, os.Stdout).Serve(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/chemikadze/gonomi/manifest"
+	"github.com/chemikadze/gonomi/manifest/workflow"
+	"os"
+	"sort"
+)
+
+const workflowUsage = "Usage: gonomi workflow plan [-dot] [-component path] [-workflow name] manifest.yml"
+
+// workflow subcommands, only plan for now
+func runWorkflow(args []string) int {
+	if len(args) == 0 || args[0] != "plan" {
+		fmt.Fprintln(os.Stderr, workflowUsage)
+		return 2
+	}
+	return runWorkflowPlan(args[1:])
+}
+
+// prints execution levels of workflow steps of every workflow.Instance
+func runWorkflowPlan(args []string) int {
+	flags := flag.NewFlagSet("workflow plan", flag.ContinueOnError)
+	asDOT := flags.Bool("dot", false, "print plan as Graphviz digraph")
+	component := flags.String("component", "", "only plan workflows of component with dotted path")
+	only := flags.String("workflow", "", "only plan workflow with name")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, workflowUsage)
+		return 2
+	}
+	app, err := readApplication(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	plans := []workflow.Plan{}
+	manifest.Walk(app, manifest.VisitorFuncs{
+		OnEnterComponent: func(id manifest.ComponentId, c manifest.Component) manifest.WalkAction {
+			leaf, ok := c.(manifest.LeafComponent)
+			if !ok || (*component != "" && id.String() != *component) {
+				return manifest.Continue
+			}
+			var workflows map[string]workflow.Workflow
+			if workflows, err = workflow.Parse(leaf); err != nil {
+				err = errors.New(fmt.Sprintf("Component %s: %s", id, err))
+				return manifest.Stop
+			}
+			names := make([]string, 0, len(workflows))
+			for name := range workflows {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				if *only != "" && name != *only {
+					continue
+				}
+				var plan workflow.Plan
+				if plan, err = workflow.NewPlan(workflows[name]); err != nil {
+					err = errors.New(fmt.Sprintf("Component %s: %s", id, err))
+					return manifest.Stop
+				}
+				plan.Name = id.String() + "." + name
+				plans = append(plans, plan)
+			}
+			return manifest.Continue
+		},
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if len(plans) == 0 {
+		fmt.Fprintln(os.Stderr, "No workflows found")
+		return 1
+	}
+	if *asDOT {
+		fmt.Print(workflow.DOT(plans...))
+		return 0
+	}
+	for _, plan := range plans {
+		fmt.Print(plan.Text())
+	}
+	return 0
+}
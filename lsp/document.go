@@ -0,0 +1,503 @@
+package lsp
+
+import (
+	"errors"
+	"fmt"
+	"github.com/chemikadze/gonomi/manifest"
+	"github.com/chemikadze/gonomi/manifest/catalog"
+	"github.com/chemikadze/gonomi/manifest/constants"
+	"github.com/chemikadze/gonomi/manifest/parsing"
+	"gopkg.in/yaml.v3"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf16"
+)
+
+// open manifest with symbols of its first YAML document
+type document struct {
+	uri   string
+	text  string
+	lines []string
+	// nil when document is not valid YAML
+	root *yaml.Node
+	// component keys by name
+	components map[string]*yaml.Node
+	// interface keys by component and interface name
+	interfaces map[string]map[string]*yaml.Node
+	pins       []pinSymbol
+	references []reference
+}
+
+type pinSymbol struct {
+	component string
+	pin       manifest.PinId
+	key       *yaml.Node
+	value     *yaml.Node
+}
+
+// scalar referring to component: binding target, service binding,
+// policy component or re-export of application interface
+type reference struct {
+	node *yaml.Node
+	// what the reference is
+	context   string
+	component string
+	iface     string
+	// byte offset of component path in scalar value
+	offset int
+}
+
+func newDocument(uri, text string) *document {
+	d := &document{
+		uri:        uri,
+		text:       text,
+		lines:      strings.Split(text, "\n"),
+		components: map[string]*yaml.Node{},
+		interfaces: map[string]map[string]*yaml.Node{},
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(text), &doc); err != nil || len(doc.Content) == 0 {
+		return d
+	}
+	d.root = doc.Content[0]
+	app := value(d.root, "application")
+	eachPair(value(app, "components"), func(key, component *yaml.Node) {
+		d.components[key.Value] = key
+		d.interfaces[key.Value] = map[string]*yaml.Node{}
+		eachPair(value(component, "interfaces"), func(ifaceKey, iface *yaml.Node) {
+			d.interfaces[key.Value][ifaceKey.Value] = ifaceKey
+			eachPair(iface, func(pinKey, pin *yaml.Node) {
+				d.pins = append(d.pins, pinSymbol{key.Value, manifest.PinId{ifaceKey.Value, pinKey.Value}, pinKey, pin})
+			})
+		})
+	})
+	for _, binding := range items(value(app, "bindings")) {
+		for _, target := range items(binding) {
+			d.addTarget(target, "binding")
+		}
+	}
+	eachPair(value(app, "interfaces"), func(_, iface *yaml.Node) {
+		eachPair(iface, func(_, pin *yaml.Node) {
+			if binding, err := manifest.ParsePinBinding(pin.Value); err == nil && pin.Kind == yaml.ScalarNode {
+				offset := strings.Index(pin.Value, "(") + 1
+				offset += len(pin.Value[offset:]) - len(strings.TrimLeft(pin.Value[offset:], " "))
+				d.references = append(d.references, reference{pin, "re-export", binding.TargetComponent, binding.TargetPin.Interface, offset})
+			}
+		})
+	})
+	eachPair(value(d.root, "services"), func(_, service *yaml.Node) {
+		for _, target := range items(value(service, "bindings")) {
+			d.addTarget(target, "service")
+		}
+	})
+	eachPair(value(d.root, "policies"), func(_, policy *yaml.Node) {
+		for _, target := range items(value(policy, "components")) {
+			if target.Kind == yaml.ScalarNode {
+				d.references = append(d.references, reference{target, "policy", target.Value, "", 0})
+			}
+		}
+	})
+	return d
+}
+
+func (d *document) addTarget(node *yaml.Node, context string) {
+	if node.Kind != yaml.ScalarNode {
+		return
+	}
+	target, err := manifest.ParseBindingTarget(node.Value)
+	if err != nil {
+		return
+	}
+	iface := ""
+	if target, ok := target.(manifest.InterfaceBindingTarget); ok {
+		iface = target.Interface
+	}
+	d.references = append(d.references, reference{node, context, manifest.BindingTargetComponent(target).String(), iface, 0})
+}
+
+// parse errors, parser warnings and problems of references and components
+func (d *document) diagnostics() []Diagnostic {
+	result := []Diagnostic{}
+	if _, err := manifest.ParseReader(strings.NewReader(d.text), ""); err != nil {
+		message, line, column := err.Error(), 0, 0
+		if err, ok := err.(parsing.PositionError); ok {
+			message, line, column = err.Message, err.Line, err.Column
+		}
+		result = append(result, Diagnostic{d.pointRange(line, column), SeverityError, "gonomi", message})
+		// problems of the rest of manifest, parse error is already reported
+		_, problems, _ := manifest.ParseWithDiagnostics(d.text, manifest.ParseOptions{})
+		for _, p := range problems {
+			if p.Line != line || p.Column != column {
+				result = append(result, Diagnostic{d.pointRange(p.Line, p.Column), severity(p.Severity), "gonomi", p.Message})
+			}
+		}
+		return result
+	}
+	if d.root == nil {
+		return result
+	}
+	app, warnings, err := manifest.ParseWithDiagnostics(d.text, manifest.ParseOptions{})
+	if err != nil {
+		return result
+	}
+	for _, w := range warnings {
+		result = append(result, Diagnostic{d.pointRange(w.Line, w.Column), SeverityWarning, "gonomi", w.Message})
+	}
+	for _, ref := range d.references {
+		// services and policies are checked by Manifest.Validate below
+		if ref.context == "service" || ref.context == "policy" {
+			continue
+		}
+		id, err := manifest.ParseComponentId(ref.component)
+		if err == nil {
+			if ref.context == "re-export" {
+				_, err = app.LookupPin(id, d.exportedPin(ref))
+			} else if ref.iface != "" {
+				_, err = app.LookupInterface(id, ref.iface)
+			} else {
+				_, err = app.Lookup(id)
+			}
+		}
+		if err != nil {
+			result = append(result, Diagnostic{d.nodeRange(ref.node), SeverityError, "gonomi", err.Error()})
+		}
+	}
+	m, err := manifest.ParseManifest(d.text)
+	problems := []error{err}
+	if err == nil {
+		problems = m.Validate()
+	}
+	for _, problem := range problems {
+		if problem, ok := problem.(manifest.SectionError); ok {
+			result = append(result, Diagnostic{d.nodeRange(d.sectionItem(problem)), SeverityError, "gonomi", problem.Error()})
+		}
+	}
+	types := catalog.Builtin()
+	for _, name := range sortedNames(d.components) {
+		leaf, ok := app.Components[name].(manifest.LeafComponent)
+		if !ok {
+			continue
+		}
+		// constants also get values checked against their pins
+		problems := types.Validate(leaf)
+		if leaf.Type.Name == constants.TypeName {
+			problems = append(problems, constants.Validate(leaf)...)
+		}
+		for _, problem := range problems {
+			result = append(result, Diagnostic{d.nodeRange(d.components[name]), SeverityWarning, "gonomi", problem.Error()})
+		}
+	}
+	return result
+}
+
+// binding target of service or component of policy problem is about
+func (d *document) sectionItem(problem manifest.SectionError) *yaml.Node {
+	key := "bindings"
+	if problem.Section == "policies" {
+		key = "components"
+	}
+	return items(value(value(value(d.root, problem.Section), problem.Name), key))[problem.Index]
+}
+
+func severity(s parsing.Severity) int {
+	if s == parsing.Error {
+		return SeverityError
+	}
+	return SeverityWarning
+}
+
+func (d *document) exportedPin(ref reference) manifest.PinId {
+	binding, _ := manifest.ParsePinBinding(ref.node.Value)
+	return binding.TargetPin
+}
+
+var (
+	pinKinds  = []string{"publish-signal", "consume-signal", "configuration", "send-command", "receive-command"}
+	dataTypes = []string{"string", "int", "bool", "list", "map", "record"}
+	pinLine   = regexp.MustCompile(`^\s*[^\s:#]+\s*:\s*(.*)$`)
+)
+
+// pin kinds and data types in pin declarations, component names in bindings;
+// text is used instead of YAML structure, which is often broken while typing
+func (d *document) completion(pos Position) []CompletionItem {
+	if pos.Line < 0 || pos.Line >= len(d.lines) {
+		return nil
+	}
+	if pos.Character < 0 {
+		pos.Character = 0
+	}
+	line := d.lines[pos.Line]
+	prefix := line[:byteOffset(line, pos.Character)]
+	parents := d.parents(pos.Line)
+	items := []CompletionItem{}
+	switch {
+	case len(parents) >= 2 && parents[len(parents)-2] == "interfaces" && pinLine.MatchString(prefix):
+		declaration := pinLine.FindStringSubmatch(prefix)[1]
+		if strings.Count(declaration, "(") > strings.Count(declaration, ")") {
+			for _, name := range dataTypes {
+				items = append(items, CompletionItem{name, KindTypeParameter, "data type"})
+			}
+		} else {
+			for _, kind := range pinKinds {
+				items = append(items, CompletionItem{kind, KindKeyword, "pin kind"})
+			}
+		}
+	case len(parents) != 0 && parents[len(parents)-1] == "bindings":
+		for _, name := range d.componentNames() {
+			items = append(items, CompletionItem{name, KindModule, "component"})
+		}
+	}
+	return items
+}
+
+// keys of mappings enclosing line judging by indentation
+func (d *document) parents(line int) []string {
+	if line < 0 || line >= len(d.lines) {
+		return nil
+	}
+	indent := indentation(d.lines[line])
+	if strings.TrimSpace(d.lines[line]) == "" {
+		indent = len(d.lines[line])
+	}
+	parents := []string{}
+	for i := line - 1; i >= 0 && indent > 0; i-- {
+		text := d.lines[i]
+		trimmed := strings.TrimSpace(text)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") || strings.HasPrefix(trimmed, "-") {
+			continue
+		}
+		if current := indentation(text); current < indent {
+			if colon := strings.Index(trimmed, ":"); colon > 0 {
+				parents = append([]string{trimmed[:colon]}, parents...)
+			}
+			indent = current
+		}
+	}
+	return parents
+}
+
+// names of components, from lines under application.components when
+// document can't be parsed
+func (d *document) componentNames() []string {
+	if d.root != nil {
+		return sortedNames(d.components)
+	}
+	names := []string{}
+	for i, line := range d.lines {
+		parents := d.parents(i)
+		trimmed := strings.TrimSpace(line)
+		if len(parents) == 2 && parents[0] == "application" && parents[1] == "components" && strings.Contains(trimmed, ":") {
+			names = append(names, trimmed[:strings.Index(trimmed, ":")])
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// component or interface declaration reference at position refers to
+func (d *document) definition(pos Position) (Location, bool) {
+	for _, ref := range d.references {
+		if !contains(d.nodeRange(ref.node), pos) {
+			continue
+		}
+		if key, ok := d.interfaces[ref.component][ref.iface]; ok {
+			return Location{d.uri, d.nodeRange(key)}, true
+		}
+		if key, ok := d.components[ref.component]; ok {
+			return Location{d.uri, d.nodeRange(key)}, true
+		}
+	}
+	return Location{}, false
+}
+
+// declaration and data type of pin under position
+func (d *document) hover(pos Position) (Hover, bool) {
+	for _, pin := range d.pins {
+		r := Range{d.nodeRange(pin.key).Start, d.nodeRange(pin.value).End}
+		if !contains(r, pos) || pin.value.Kind != yaml.ScalarNode {
+			continue
+		}
+		pinType, err := manifest.ParsePinType(pin.value.Value)
+		if err != nil {
+			continue
+		}
+		s := fmt.Sprintf("**%s.%s**: `%s`", pin.pin.Interface, pin.pin.Pin, pinType)
+		switch t := pinType.PinType.(type) {
+		case manifest.SignalPin:
+			s += "\n\ndata type: `" + dataTypeName(t.DataType) + "`"
+		case manifest.ConfigurationPin:
+			s += "\n\ndata type: `" + dataTypeName(t.DataType) + "`"
+		case manifest.CommandPin:
+			s += "\n\narguments: `" + t.Arguments.DataTypeName() + "`"
+		}
+		return Hover{MarkupContent{"markdown", s}, &r}, true
+	}
+	return Hover{}, false
+}
+
+func dataTypeName(t interface{ DataTypeName() string }) string {
+	if t == nil {
+		return "any"
+	}
+	return t.DataTypeName()
+}
+
+// renames component under position, which is either its declaration
+// or reference to it, together with every reference
+func (d *document) rename(pos Position, newName string) ([]TextEdit, error) {
+	if _, err := manifest.ParseComponentId(newName); err != nil || strings.ContainsAny(newName, ".#:") {
+		return nil, errors.New(fmt.Sprintf("Invalid component name: %s", newName))
+	}
+	old := ""
+	for name, key := range d.components {
+		if contains(d.nodeRange(key), pos) {
+			old = name
+		}
+	}
+	for _, ref := range d.references {
+		if contains(d.nodeRange(ref.node), pos) {
+			old = strings.Split(ref.component, ".")[0]
+		}
+	}
+	if old == "" {
+		return nil, errors.New("No component at position")
+	}
+	if _, ok := d.components[newName]; ok {
+		return nil, errors.New(fmt.Sprintf("Component %s already exists", newName))
+	}
+	edits := []TextEdit{}
+	if key, ok := d.components[old]; ok {
+		edits = append(edits, TextEdit{d.nodeRange(key), newName})
+	}
+	for _, ref := range d.references {
+		if strings.Split(ref.component, ".")[0] != old {
+			continue
+		}
+		start := d.nodeRange(ref.node).Start
+		start.Character += quoteWidth(ref.node) + utf16Len(ref.node.Value[:ref.offset])
+		end := start
+		end.Character += utf16Len(old)
+		edits = append(edits, TextEdit{Range{start, end}, newName})
+	}
+	return edits, nil
+}
+
+// zero-width range at 1-based line and column, start of document if unknown
+func (d *document) pointRange(line, column int) Range {
+	if line == 0 {
+		return Range{}
+	}
+	if column == 0 {
+		column = 1
+	}
+	p := d.position(line, column)
+	return Range{p, p}
+}
+
+// range of single-line scalar including quotes
+func (d *document) nodeRange(node *yaml.Node) Range {
+	start := d.position(node.Line, node.Column)
+	end := start
+	end.Character += utf16Len(node.Value) + 2*quoteWidth(node)
+	return Range{start, end}
+}
+
+// position of 1-based line and column counted in runes as YAML does,
+// LSP counts characters in UTF-16 code units
+func (d *document) position(line, column int) Position {
+	text := ""
+	if line-1 < len(d.lines) {
+		text = d.lines[line-1]
+	}
+	runes := []rune(text)
+	if column-1 > len(runes) {
+		return Position{line - 1, utf16Len(text) + column - 1 - len(runes)}
+	}
+	return Position{line - 1, utf16Len(string(runes[:column-1]))}
+}
+
+func utf16Len(s string) int {
+	return len(utf16.Encode([]rune(s)))
+}
+
+func quoteWidth(node *yaml.Node) int {
+	if node.Style&(yaml.DoubleQuotedStyle|yaml.SingleQuotedStyle) != 0 {
+		return 1
+	}
+	return 0
+}
+
+func contains(r Range, pos Position) bool {
+	if pos.Line < r.Start.Line || pos.Line > r.End.Line {
+		return false
+	}
+	if pos.Line == r.Start.Line && pos.Character < r.Start.Character {
+		return false
+	}
+	return pos.Line != r.End.Line || pos.Character <= r.End.Character
+}
+
+// byte offset of character in line, characters are counted in UTF-16 code units
+func byteOffset(line string, character int) int {
+	units := 0
+	for offset, r := range line {
+		if units >= character {
+			return offset
+		}
+		units += len(utf16.Encode([]rune{r}))
+	}
+	return len(line)
+}
+
+func indentation(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " "))
+}
+
+func value(node *yaml.Node, key string) *yaml.Node {
+	var result *yaml.Node
+	eachPair(node, func(k, v *yaml.Node) {
+		if k.Value == key {
+			result = v
+		}
+	})
+	return result
+}
+
+func eachPair(node *yaml.Node, f func(key, value *yaml.Node)) {
+	node = alias(node)
+	if node == nil || node.Kind != yaml.MappingNode {
+		return
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		f(alias(node.Content[i]), alias(node.Content[i+1]))
+	}
+}
+
+func items(node *yaml.Node) []*yaml.Node {
+	node = alias(node)
+	if node == nil || node.Kind != yaml.SequenceNode {
+		return nil
+	}
+	result := make([]*yaml.Node, 0, len(node.Content))
+	for _, item := range node.Content {
+		result = append(result, alias(item))
+	}
+	return result
+}
+
+func alias(node *yaml.Node) *yaml.Node {
+	for node != nil && node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	return node
+}
+
+func sortedNames(m map[string]*yaml.Node) []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,251 @@
+package lsp
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const manifestText = `application:
+    interfaces:
+        sql:
+            url: bind(db#sql.url)
+    components:
+        db:
+            type: test.Database
+            interfaces:
+                sql:
+                    url: publish-signal(string)
+                    query: receive-command(string q, int limit)
+        web:
+            type: test.Web
+            interfaces:
+                sql:
+                    url: consume-signal(string)
+    bindings:
+        - [web#sql, db]
+        - [web, cache]
+policies:
+    scaling:
+        components: ["db"]
+`
+
+func TestDiagnostics(t *testing.T) {
+	d := newDocument("file:///app.yml", manifestText)
+	expected := []Diagnostic{
+		{Range{Position{18, 16}, Position{18, 21}}, SeverityError, "gonomi", "Component cache: no component cache in application"},
+	}
+	if diagnostics := d.diagnostics(); !reflect.DeepEqual(diagnostics, expected) {
+		t.Errorf("\nReported: %v\nExpect:   %v", diagnostics, expected)
+	}
+	d = newDocument("file:///app.yml", "application:\n    components:\n        x:\n            interfaces:\n                i:\n                    p: plubish-signal(string)\n")
+	expected = []Diagnostic{
+		{Range{Position{5, 23}, Position{5, 23}}, SeverityError, "gonomi", "Unknown pin type: plubish-signal"},
+	}
+	if diagnostics := d.diagnostics(); !reflect.DeepEqual(diagnostics, expected) {
+		t.Errorf("\nReported: %v\nExpect:   %v", diagnostics, expected)
+	}
+	d = newDocument("file:///app.yml", "application:\n    components:\n        x:\n            typ: test.X\n            interfaces:\n                i:\n                    p: publish-signal(lst<string>)\n")
+	expected = []Diagnostic{
+		{Range{Position{6, 23}, Position{6, 23}}, SeverityError, "gonomi", "Malformed data type in publish-signal(lst<string>): Unknown type lst"},
+		{Range{Position{3, 12}, Position{3, 12}}, SeverityWarning, "gonomi", "Component x: unknown key typ"},
+	}
+	if diagnostics := d.diagnostics(); !reflect.DeepEqual(diagnostics, expected) {
+		t.Errorf("\nReported: %v\nExpect:   %v", diagnostics, expected)
+	}
+	d = newDocument("file:///app.yml", "application:\n    components:\n        x:\n            type: cobalt.common.Constants\n            interfacs: {}\n")
+	messages := []string{}
+	for _, diagnostic := range d.diagnostics() {
+		messages = append(messages, diagnostic.Message)
+	}
+	expectedMessages := []string{"Component x: unknown key interfacs"}
+	if !reflect.DeepEqual(messages, expectedMessages) {
+		t.Errorf("\nReported: %q\nExpect:   %q", messages, expectedMessages)
+	}
+	d = newDocument("file:///app.yml", `application:
+    components:
+        x:
+            type: cobalt.common.Constants
+            configuration:
+                out.port: 80
+            interfaces:
+                out:
+                    port: publish-signal(int)
+`)
+	if diagnostics := d.diagnostics(); len(diagnostics) != 0 {
+		t.Errorf("No diagnostics expected for constants, got %v", diagnostics)
+	}
+	d = newDocument("file:///app.yml", `application:
+    components:
+        x:
+            type: cobalt.common.Constants
+            configuration:
+                out.port: "80"
+            interfaces:
+                out:
+                    port: publish-signal(int)
+                    reset: receive-command()
+`)
+	messages = []string{}
+	for _, diagnostic := range d.diagnostics() {
+		messages = append(messages, diagnostic.Message)
+	}
+	expectedMessages = []string{
+		"cobalt.common.Constants doesn't allow receive-command pin out.reset",
+		"Constant out.port: Expected int, got string",
+		"Constant out.reset: receive-command() is not a publish-signal pin",
+	}
+	if !reflect.DeepEqual(messages, expectedMessages) {
+		t.Errorf("\nReported: %q\nExpect:   %q", messages, expectedMessages)
+	}
+	d = newDocument("file:///app.yml", manifestText+`    backup:
+        components: [cache]
+services:
+    keystore:
+        bindings: [db#sql, "db#", web#keys]
+`)
+	expected = []Diagnostic{
+		{Range{Position{18, 16}, Position{18, 21}}, SeverityError, "gonomi", "Component cache: no component cache in application"},
+		{Range{Position{26, 27}, Position{26, 32}}, SeverityError, "gonomi", "Service keystore: Unexpected binding target: db#"},
+	}
+	if diagnostics := d.diagnostics(); !reflect.DeepEqual(diagnostics, expected) {
+		t.Errorf("\nReported: %v\nExpect:   %v", diagnostics, expected)
+	}
+	d = newDocument("file:///app.yml", manifestText+`    backup:
+        components: [cache]
+services:
+    keystore:
+        bindings: [db#sql, web#keys]
+`)
+	expected = []Diagnostic{
+		{Range{Position{18, 16}, Position{18, 21}}, SeverityError, "gonomi", "Component cache: no component cache in application"},
+		{Range{Position{26, 27}, Position{26, 35}}, SeverityError, "gonomi", "Service keystore: Component web: no interface keys"},
+		{Range{Position{23, 21}, Position{23, 26}}, SeverityError, "gonomi", "Policy backup: Component cache: no component cache in application"},
+	}
+	if diagnostics := d.diagnostics(); !reflect.DeepEqual(diagnostics, expected) {
+		t.Errorf("\nReported: %v\nExpect:   %v", diagnostics, expected)
+	}
+}
+
+// characters are UTF-16 code units, so emoji before position counts twice
+func TestDiagnosticsSurrogatePairs(t *testing.T) {
+	d := newDocument("file:///app.yml", "application:\n    components:\n        web:\n            type: test.Web\n    bindings:\n        - [\"🚀\", cache]\n")
+	expected := []Diagnostic{
+		{Range{Position{5, 11}, Position{5, 15}}, SeverityError, "gonomi", "Component 🚀: no component 🚀 in application"},
+		{Range{Position{5, 17}, Position{5, 22}}, SeverityError, "gonomi", "Component cache: no component cache in application"},
+	}
+	if diagnostics := d.diagnostics(); !reflect.DeepEqual(diagnostics, expected) {
+		t.Errorf("\nReported: %v\nExpect:   %v", diagnostics, expected)
+	}
+	d = newDocument("file:///app.yml", "application:\n    components:\n        web:\n            interfaces: {i: {\"🚀\": publish-signal(strin)}}\n")
+	expected = []Diagnostic{
+		{Range{Position{3, 35}, Position{3, 35}}, SeverityError, "gonomi", "Malformed data type in publish-signal(strin): Unknown type strin"},
+	}
+	if diagnostics := d.diagnostics(); !reflect.DeepEqual(diagnostics, expected) {
+		t.Errorf("\nReported: %v\nExpect:   %v", diagnostics, expected)
+	}
+}
+
+func TestByteOffset(t *testing.T) {
+	cases := map[int]int{0: 0, 1: 1, 3: 5, 4: 6, 10: 6}
+	for character, expected := range cases {
+		if offset := byteOffset("a🚀b", character); offset != expected {
+			t.Errorf("%d: expected offset %d, got %d", character, expected, offset)
+		}
+	}
+}
+
+func labels(items []CompletionItem) []string {
+	result := []string{}
+	for _, item := range items {
+		result = append(result, item.Label)
+	}
+	return result
+}
+
+func TestCompletion(t *testing.T) {
+	text := strings.Replace(manifestText, "url: consume-signal(string)", "url: consume-signal(", 1)
+	d := newDocument("file:///app.yml", text)
+	if items := labels(d.completion(Position{15, 24})); !reflect.DeepEqual(items, pinKinds) {
+		t.Errorf("Pin kinds expected, got %v", items)
+	}
+	if items := labels(d.completion(Position{15, 40})); !reflect.DeepEqual(items, dataTypes) {
+		t.Errorf("Data types expected, got %v", items)
+	}
+	if items := labels(d.completion(Position{18, 16})); !reflect.DeepEqual(items, []string{"db", "web"}) {
+		t.Errorf("Components expected, got %v", items)
+	}
+	if items := d.completion(Position{6, 18}); len(items) != 0 {
+		t.Errorf("Nothing expected for type, got %v", labels(items))
+	}
+	if items := d.completion(Position{-1, 0}); len(items) != 0 {
+		t.Errorf("Nothing expected before first line, got %v", labels(items))
+	}
+	if items := labels(d.completion(Position{18, -3})); !reflect.DeepEqual(items, []string{"db", "web"}) {
+		t.Errorf("Components expected at start of line, got %v", items)
+	}
+}
+
+func TestDefinition(t *testing.T) {
+	d := newDocument("file:///app.yml", manifestText)
+	cases := map[Position]Range{
+		// web#sql is interface of web
+		{17, 12}: {Position{14, 16}, Position{14, 19}},
+		{17, 20}: {Position{5, 8}, Position{5, 10}},
+		{3, 20}:  {Position{8, 16}, Position{8, 19}},
+		{21, 22}: {Position{5, 8}, Position{5, 10}},
+	}
+	for pos, expected := range cases {
+		location, ok := d.definition(pos)
+		if !ok || location.Range != expected || location.URI != "file:///app.yml" {
+			t.Errorf("%v: unexpected definition %v", pos, location)
+		}
+	}
+	if _, ok := d.definition(Position{18, 18}); ok {
+		t.Error("Unknown component should not be found")
+	}
+}
+
+func TestHover(t *testing.T) {
+	d := newDocument("file:///app.yml", manifestText)
+	hover, ok := d.hover(Position{9, 30})
+	if !ok || hover.Contents.Value != "**sql.url**: `publish-signal(string)`\n\ndata type: `string`" {
+		t.Errorf("Unexpected hover: %v", hover)
+	}
+	hover, ok = d.hover(Position{10, 22})
+	if !ok || hover.Contents.Value != "**sql.query**: `receive-command(int limit, string q)`\n\narguments: `record<int limit, string q>`" {
+		t.Errorf("Unexpected hover: %v", hover)
+	}
+	if _, ok := d.hover(Position{6, 20}); ok {
+		t.Error("Hover expected only for pins")
+	}
+}
+
+func TestRename(t *testing.T) {
+	d := newDocument("file:///app.yml", manifestText)
+	edits, err := d.rename(Position{17, 20}, "database")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(manifestText, "\n")
+	for i := len(edits) - 1; i >= 0; i-- {
+		r := edits[i].Range
+		line := lines[r.Start.Line]
+		lines[r.Start.Line] = line[:r.Start.Character] + edits[i].NewText + line[r.End.Character:]
+	}
+	renamed := strings.Join(lines, "\n")
+	for _, expected := range []string{"url: bind(database#sql.url)", "        database:\n", "- [web#sql, database]", `components: ["database"]`} {
+		if !strings.Contains(renamed, expected) {
+			t.Errorf("%q expected in\n%s", expected, renamed)
+		}
+	}
+	if _, err := d.rename(Position{17, 20}, "web"); err == nil {
+		t.Error("Renaming to existing component should fail")
+	}
+	if _, err := d.rename(Position{17, 20}, "a.b"); err == nil {
+		t.Error("Renaming to path should fail")
+	}
+	if _, err := d.rename(Position{0, 0}, "x"); err == nil {
+		t.Error("Renaming outside of component should fail")
+	}
+}
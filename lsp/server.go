@@ -0,0 +1,173 @@
+// Package lsp implements Language Server Protocol for manifests over
+// stdin and stdout: diagnostics, completion of pin kinds and data types,
+// go-to-definition of binding targets, hover of pins and renaming of
+// components. Documents are synchronized in full on every change.
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+)
+
+type Server struct {
+	in   *bufio.Reader
+	out  io.Writer
+	docs map[string]*document
+	// shutdown request was received, only exit is expected
+	shutdown bool
+}
+
+func NewServer(in io.Reader, out io.Writer) *Server {
+	return &Server{in: bufio.NewReader(in), out: out, docs: make(map[string]*document)}
+}
+
+var capabilities = map[string]interface{}{
+	"capabilities": map[string]interface{}{
+		// full document on every change
+		"textDocumentSync":   1,
+		"completionProvider": map[string]interface{}{"triggerCharacters": []string{":", "(", "<", ",", " ", "["}},
+		"definitionProvider": true,
+		"hoverProvider":      true,
+		"renameProvider":     true,
+	},
+	"serverInfo": map[string]string{"name": "gonomi"},
+}
+
+// handles messages until exit notification or end of input,
+// exit without prior shutdown request is an error
+func (s *Server) Serve() error {
+	for {
+		m, parseErr, err := readMessage(s.in)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if parseErr != nil {
+			if err := writeMessage(s.out, errorResponse{"2.0", nil, *parseErr}); err != nil {
+				return err
+			}
+			continue
+		}
+		if m.Method == "exit" {
+			if !s.shutdown {
+				return errors.New("Exit before shutdown")
+			}
+			return nil
+		}
+		if err := s.handle(m); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *Server) handle(m message) error {
+	if s.shutdown && m.Id != nil {
+		return s.fail(m, invalidRequest, "Server is shut down")
+	}
+	switch m.Method {
+	case "initialize":
+		return s.reply(m, capabilities)
+	case "shutdown":
+		s.shutdown = true
+		return s.reply(m, nil)
+	case "textDocument/didOpen":
+		var params DidOpenTextDocumentParams
+		if json.Unmarshal(m.Params, &params) != nil {
+			return nil
+		}
+		return s.update(params.TextDocument.URI, params.TextDocument.Text)
+	case "textDocument/didChange":
+		var params DidChangeTextDocumentParams
+		if json.Unmarshal(m.Params, &params) != nil || len(params.ContentChanges) == 0 {
+			return nil
+		}
+		return s.update(params.TextDocument.URI, params.ContentChanges[len(params.ContentChanges)-1].Text)
+	case "textDocument/didClose":
+		var params DidCloseTextDocumentParams
+		if json.Unmarshal(m.Params, &params) != nil {
+			return nil
+		}
+		delete(s.docs, params.TextDocument.URI)
+		return s.notify("textDocument/publishDiagnostics", PublishDiagnosticsParams{params.TextDocument.URI, []Diagnostic{}})
+	case "textDocument/completion":
+		doc, params, err := s.position(m)
+		if doc == nil {
+			return err
+		}
+		return s.reply(m, doc.completion(params.Position))
+	case "textDocument/definition":
+		doc, params, err := s.position(m)
+		if doc == nil {
+			return err
+		}
+		if location, ok := doc.definition(params.Position); ok {
+			return s.reply(m, location)
+		}
+		return s.reply(m, nil)
+	case "textDocument/hover":
+		doc, params, err := s.position(m)
+		if doc == nil {
+			return err
+		}
+		if hover, ok := doc.hover(params.Position); ok {
+			return s.reply(m, hover)
+		}
+		return s.reply(m, nil)
+	case "textDocument/rename":
+		var params RenameParams
+		if err := json.Unmarshal(m.Params, &params); err != nil {
+			return s.fail(m, invalidParams, err.Error())
+		}
+		doc, ok := s.docs[params.TextDocument.URI]
+		if !ok {
+			return s.fail(m, invalidParams, "Unknown document: "+params.TextDocument.URI)
+		}
+		edits, err := doc.rename(params.Position, params.NewName)
+		if err != nil {
+			return s.fail(m, invalidRequest, err.Error())
+		}
+		return s.reply(m, WorkspaceEdit{map[string][]TextEdit{doc.uri: edits}})
+	}
+	if m.Id != nil {
+		return s.fail(m, methodNotFound, "Method not found: "+m.Method)
+	}
+	// notifications like initialized and $/cancelRequest
+	return nil
+}
+
+// document and position of textDocument/* request; nil document means
+// request is already answered with error
+func (s *Server) position(m message) (*document, TextDocumentPositionParams, error) {
+	var params TextDocumentPositionParams
+	if err := json.Unmarshal(m.Params, &params); err != nil {
+		return nil, params, s.fail(m, invalidParams, err.Error())
+	}
+	doc, ok := s.docs[params.TextDocument.URI]
+	if !ok {
+		return nil, params, s.fail(m, invalidParams, "Unknown document: "+params.TextDocument.URI)
+	}
+	return doc, params, nil
+}
+
+// analyzes new text of document and publishes its diagnostics
+func (s *Server) update(uri, text string) error {
+	doc := newDocument(uri, text)
+	s.docs[uri] = doc
+	return s.notify("textDocument/publishDiagnostics", PublishDiagnosticsParams{uri, doc.diagnostics()})
+}
+
+func (s *Server) reply(m message, result interface{}) error {
+	return writeMessage(s.out, response{"2.0", m.Id, result})
+}
+
+func (s *Server) fail(m message, code int, text string) error {
+	return writeMessage(s.out, errorResponse{"2.0", m.Id, responseError{code, text}})
+}
+
+func (s *Server) notify(method string, params interface{}) error {
+	return writeMessage(s.out, notification{"2.0", method, params})
+}
@@ -0,0 +1,115 @@
+package lsp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// frames every message and runs server until end of input
+func session(t *testing.T, messages ...string) ([]map[string]interface{}, error) {
+	var in bytes.Buffer
+	for _, m := range messages {
+		fmt.Fprintf(&in, "Content-Length: %d\r\n\r\n%s", len(m), m)
+	}
+	var out bytes.Buffer
+	err := NewServer(&in, &out).Serve()
+	replies := []map[string]interface{}{}
+	r := bufio.NewReader(&out)
+	for {
+		var header string
+		if _, err := fmt.Fscanf(r, "Content-Length: %s\r\n\r\n", &header); err != nil {
+			break
+		}
+		var length int
+		fmt.Sscan(header, &length)
+		body := make([]byte, length)
+		if _, err := r.Read(body); err != nil {
+			t.Fatal(err)
+		}
+		var reply map[string]interface{}
+		if err := json.Unmarshal(body, &reply); err != nil {
+			t.Fatal(err)
+		}
+		replies = append(replies, reply)
+	}
+	return replies, err
+}
+
+func TestSession(t *testing.T) {
+	text, _ := json.Marshal(manifestText)
+	replies, err := session(t,
+		`{"jsonrpc": "2.0", "id": 1, "method": "initialize", "params": {}}`,
+		`{"jsonrpc": "2.0", "method": "initialized", "params": {}}`,
+		`{"jsonrpc": "2.0", "method": "textDocument/didOpen", "params": {"textDocument": {"uri": "file:///app.yml", "version": 1, "text": `+string(text)+`}}}`,
+		`{"jsonrpc": "2.0", "id": 2, "method": "textDocument/hover", "params": {"textDocument": {"uri": "file:///app.yml"}, "position": {"line": 9, "character": 30}}}`,
+		`{"jsonrpc": "2.0", "id": 3, "method": "textDocument/definition", "params": {"textDocument": {"uri": "file:///app.yml"}, "position": {"line": 0, "character": 0}}}`,
+		`{"jsonrpc": "2.0", "id": 4, "method": "textDocument/rename", "params": {"textDocument": {"uri": "file:///app.yml"}, "position": {"line": 5, "character": 9}, "newName": "web"}}`,
+		`{"jsonrpc": "2.0", "id": 5, "method": "workspace/symbol", "params": {}}`,
+		`{"jsonrpc": "2.0", "id": 6, "method": "shutdown"}`,
+		`{"jsonrpc": "2.0", "method": "exit"}`,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(replies) != 7 {
+		t.Fatalf("Unexpected replies: %v", replies)
+	}
+	if capabilities, ok := replies[0]["result"].(map[string]interface{})["capabilities"].(map[string]interface{}); !ok || capabilities["hoverProvider"] != true {
+		t.Errorf("Unexpected initialize result: %v", replies[0])
+	}
+	if replies[1]["method"] != "textDocument/publishDiagnostics" {
+		t.Errorf("Diagnostics expected: %v", replies[1])
+	} else if diagnostics := replies[1]["params"].(map[string]interface{})["diagnostics"].([]interface{}); len(diagnostics) != 1 {
+		t.Errorf("Unexpected diagnostics: %v", diagnostics)
+	}
+	if hover, ok := replies[2]["result"].(map[string]interface{}); !ok || !strings.Contains(fmt.Sprint(hover["contents"]), "publish-signal(string)") {
+		t.Errorf("Unexpected hover: %v", replies[2])
+	}
+	if result, ok := replies[3]["result"]; !ok || result != nil {
+		t.Errorf("Null definition expected: %v", replies[3])
+	}
+	if replies[4]["error"] == nil {
+		t.Errorf("Rename to existing component should fail: %v", replies[4])
+	}
+	if e, ok := replies[5]["error"].(map[string]interface{}); !ok || e["code"] != float64(methodNotFound) {
+		t.Errorf("Unknown method should fail: %v", replies[5])
+	}
+	if result, ok := replies[6]["result"]; !ok || result != nil {
+		t.Errorf("Null shutdown result expected: %v", replies[6])
+	}
+}
+
+func TestNegativePosition(t *testing.T) {
+	text, _ := json.Marshal(manifestText)
+	replies, err := session(t,
+		`{"jsonrpc": "2.0", "method": "textDocument/didOpen", "params": {"textDocument": {"uri": "file:///app.yml", "version": 1, "text": `+string(text)+`}}}`,
+		`{"jsonrpc": "2.0", "id": 1, "method": "textDocument/completion", "params": {"textDocument": {"uri": "file:///app.yml"}, "position": {"line": -1, "character": -1}}}`,
+		`{"jsonrpc": "2.0", "id": 2, "method": "textDocument/completion", "params": {"textDocument": {"uri": "file:///app.yml"}, "position": {"line": 3, "character": -5}}}`,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(replies) != 3 || replies[1]["error"] != nil || replies[2]["error"] != nil {
+		t.Errorf("Unexpected replies: %v", replies)
+	}
+}
+
+func TestExitBeforeShutdown(t *testing.T) {
+	if _, err := session(t, `{"jsonrpc": "2.0", "method": "exit"}`); err == nil {
+		t.Error("Error expected")
+	}
+}
+
+func TestMalformedMessage(t *testing.T) {
+	replies, err := session(t, `{"jsonrpc": `)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(replies) != 1 || replies[0]["error"].(map[string]interface{})["code"] != float64(parseError) {
+		t.Errorf("Parse error expected: %v", replies)
+	}
+}
@@ -1,10 +1,65 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"github.com/chemikadze/gonomi/manifest"
+	"os"
+	"sort"
+	"text/tabwriter"
 )
 
+type command struct {
+	run  func(args []string) int
+	args string
+	help string
+}
+
+var commands = map[string]command{
+	"fmt":      {runFmt, "[-w] [-d] [-l] [file ...]", "rewrite manifests in canonical form"},
+	"diff":     {runDiff, "[-json] old.yml new.yml", "compare component models of manifests"},
+	"compat":   {runCompat, "[-json] [-all] old.yml new.yml", "fail on interface changes breaking bound components"},
+	"lint":     {runLint, "manifest.yml", "report unused pins, unbound interfaces and other likely mistakes"},
+	"lsp":      {runLSP, "", "serve Language Server Protocol over stdin and stdout"},
+	"workflow": {runWorkflow, "plan [-dot] [-component path] [-workflow name] manifest.yml", "show execution order of workflow steps"},
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage: gonomi <command> [arguments]")
+	fmt.Fprintln(os.Stderr, "Commands:")
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	w := tabwriter.NewWriter(os.Stderr, 0, 4, 2, ' ', 0)
+	for _, name := range names {
+		fmt.Fprintf(w, "    %s %s\t%s\n", name, commands[name].args, commands[name].help)
+	}
+	w.Flush()
+}
+
+// reads manifest with exactly one application, imports are resolved
+func readApplication(path string) (manifest.Application, error) {
+	loaded, err := manifest.LoadFile(path)
+	if err != nil {
+		return manifest.Application{}, err
+	}
+	if len(loaded) != 1 {
+		return manifest.Application{}, errors.New(fmt.Sprintf("%s: expected one application, got %d", path, len(loaded)))
+	}
+	return loaded[0].Application, nil
+}
+
 func main() {
-	fmt.Println(manifest.Application{})
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+	cmd, ok := commands[os.Args[1]]
+	if !ok {
+		usage()
+		os.Exit(2)
+	}
+	os.Exit(cmd.run(os.Args[2:]))
 }
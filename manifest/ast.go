@@ -3,6 +3,7 @@ package manifest
 import (
 	"github.com/chemikadze/gonomi/manifest/datatype"
 	"reflect"
+	"strings"
 )
 
 const (
@@ -131,6 +132,40 @@ func (d direction) IsReceive() bool {
 	return bool(!d)
 }
 
+// pin declaration as written in manifest, e.g. publish-signal(string)
+func (p DirectedPinType) String() string {
+	switch t := p.PinType.(type) {
+	case SignalPin:
+		if p.Direction.IsSend() {
+			return "publish-signal(" + dataTypeName(t.DataType) + ")"
+		}
+		return "consume-signal(" + dataTypeName(t.DataType) + ")"
+	case ConfigurationPin:
+		return "configuration(" + dataTypeName(t.DataType) + ")"
+	case CommandPin:
+		body := t.Arguments.BodyName()
+		if len(t.Progress.Fields) != 0 {
+			body += " => " + t.Progress.BodyName() + " => " + t.Result.BodyName()
+		} else if len(t.Result.Fields) != 0 {
+			body += " => " + t.Result.BodyName()
+		}
+		body = strings.TrimSpace(body)
+		if p.Direction.IsSend() {
+			return "send-command(" + body + ")"
+		}
+		return "receive-command(" + body + ")"
+	}
+	return ""
+}
+
+// untyped pins have nil data type
+func dataTypeName(t datatype.DataType) string {
+	if t == nil {
+		return ""
+	}
+	return t.DataTypeName()
+}
+
 type PinType interface {
 	PinTypeName() string
 }
@@ -0,0 +1,229 @@
+package manifest
+
+import (
+	"fmt"
+	"github.com/chemikadze/gonomi/manifest/datatype"
+	"github.com/chemikadze/gonomi/manifest/parsing"
+	"strings"
+)
+
+// builds the same AST as Parse does for equivalent manifest:
+//
+//	app, err := NewApp().
+//	    Component("x", "test.Component").
+//	        Interface("i").Publish("p", "string").Require().
+//	    Component("y", "test.Component").
+//	        Interface("i").Consume("p", "string").
+//	    Bind("x", "y").
+//	    Build()
+//
+// first error stops building and is returned by Build
+type AppBuilder struct {
+	app      Application
+	bindings [][2]string
+	err      error
+}
+
+type ComponentBuilder struct {
+	*AppBuilder
+	name string
+}
+
+type InterfaceBuilder struct {
+	*ComponentBuilder
+	iface string
+}
+
+func NewApp() *AppBuilder {
+	return &AppBuilder{}
+}
+
+func (b *AppBuilder) fail(format string, args ...interface{}) {
+	if b.err == nil {
+		b.err = parsing.ManifestError{fmt.Sprintf(format, args...), 0, 0}
+	}
+}
+
+// starts new leaf component, following calls configure it
+func (b *AppBuilder) Component(name, typeName string) *ComponentBuilder {
+	c := &ComponentBuilder{b, name}
+	if b.err != nil {
+		return c
+	}
+	if name == "" || typeName == "" {
+		b.fail("Component name and type should not be empty")
+		return c
+	}
+	if _, ok := b.app.Components[name]; ok {
+		b.fail("Duplicate component: %s", name)
+		return c
+	}
+	if b.app.Components == nil {
+		b.app.Components = make(map[string]Component)
+	}
+	b.app.Components[name] = LeafComponent{
+		Type:          Type{typeName},
+		Configuration: Configuration{},
+		Interfaces:    map[string]LeafInterface{},
+	}
+	return c
+}
+
+// binds two targets like a or a#iface, targets are checked on Build
+func (b *AppBuilder) Bind(left, right string) *AppBuilder {
+	if b.err != nil {
+		return b
+	}
+	binding, err := parseBinding(left, right)
+	if err != nil {
+		b.err = err
+		return b
+	}
+	b.app.Bindings = append(b.app.Bindings, binding)
+	b.bindings = append(b.bindings, [2]string{left, right})
+	return b
+}
+
+func (b *AppBuilder) Build() (Application, error) {
+	if b.err != nil {
+		return Application{}, b.err
+	}
+	for i, binding := range b.app.Bindings {
+		for _, target := range []BindingTarget{binding.Left, binding.Right} {
+			if _, _, err := b.app.bindingSide(ComponentId{}, target); err != nil {
+				return Application{}, parsing.ManifestError{fmt.Sprintf("Binding %v: %s", b.bindings[i], err), 0, 0}
+			}
+		}
+	}
+	return b.app.copy(), nil
+}
+
+// application with maps of its own, so following calls of builder
+// don't change applications it has built
+func (a Application) copy() Application {
+	result := Application{CompositeComponent{Bindings: append([]Binding(nil), a.Bindings...)}}
+	if a.Components == nil {
+		return result
+	}
+	result.Components = make(map[string]Component, len(a.Components))
+	for name, c := range a.Components {
+		leaf := c.(LeafComponent)
+		configuration := make(Configuration, len(leaf.Configuration))
+		for key, value := range leaf.Configuration {
+			configuration[key] = value
+		}
+		interfaces := make(map[string]LeafInterface, len(leaf.Interfaces))
+		for name, iface := range leaf.Interfaces {
+			pins := make(map[string]DirectedPinType, len(iface.Pins))
+			for pin, pinType := range iface.Pins {
+				pins[pin] = pinType
+			}
+			interfaces[name] = LeafInterface{pins, iface.Required}
+		}
+		leaf.Configuration, leaf.Interfaces = configuration, interfaces
+		result.Components[name] = leaf
+	}
+	return result
+}
+
+func (c *ComponentBuilder) leaf() LeafComponent {
+	return c.app.Components[c.name].(LeafComponent)
+}
+
+func (c *ComponentBuilder) Configure(key string, value interface{}) *ComponentBuilder {
+	if c.err != nil {
+		return c
+	}
+	if _, ok := c.leaf().Configuration[key]; ok {
+		c.fail("Component %s: duplicate configuration key %s", c.name, key)
+		return c
+	}
+	c.leaf().Configuration[key] = value
+	return c
+}
+
+// starts new interface of current component, following calls add pins to it
+func (c *ComponentBuilder) Interface(name string) *InterfaceBuilder {
+	i := &InterfaceBuilder{c, name}
+	if c.err != nil {
+		return i
+	}
+	if _, ok := c.leaf().Interfaces[name]; ok {
+		c.fail("Component %s: duplicate interface %s", c.name, name)
+		return i
+	}
+	c.leaf().Interfaces[name] = LeafInterface{map[string]DirectedPinType{}, false}
+	return i
+}
+
+// marks current interface as required
+func (i *InterfaceBuilder) Require() *InterfaceBuilder {
+	if i.err != nil {
+		return i
+	}
+	iface := i.leaf().Interfaces[i.iface]
+	iface.Required = true
+	i.leaf().Interfaces[i.iface] = iface
+	return i
+}
+
+// adds pin by its declaration, e.g. publish-signal(string)
+func (i *InterfaceBuilder) Pin(name, declaration string) *InterfaceBuilder {
+	if i.err != nil {
+		return i
+	}
+	pin, err := parseDirectedPinType(declaration)
+	if err != nil {
+		i.fail("Component %s: pin %s.%s: %s", i.name, i.iface, name, err)
+		return i
+	}
+	return i.addPin(name, pin)
+}
+
+// adds publish-signal pin, empty data type makes untyped pin like publish-signal()
+func (i *InterfaceBuilder) Publish(name, dataType string) *InterfaceBuilder {
+	return i.signal(name, Sends, dataType)
+}
+
+// adds consume-signal pin, empty data type makes untyped pin like consume-signal()
+func (i *InterfaceBuilder) Consume(name, dataType string) *InterfaceBuilder {
+	return i.signal(name, Receives, dataType)
+}
+
+// adds send-command pin, arguments are given as record body, e.g. "string x, int y",
+// optionally followed by result, e.g. "string x => int y"
+func (i *InterfaceBuilder) Send(name, arguments string) *InterfaceBuilder {
+	return i.Pin(name, "send-command("+arguments+")")
+}
+
+// adds receive-command pin, arguments are given as record body, e.g. "string x, int y",
+// optionally followed by result, e.g. "string x => int y"
+func (i *InterfaceBuilder) Receive(name, arguments string) *InterfaceBuilder {
+	return i.Pin(name, "receive-command("+arguments+")")
+}
+
+func (i *InterfaceBuilder) signal(name string, direction Direction, dataType string) *InterfaceBuilder {
+	if i.err != nil {
+		return i
+	}
+	var t datatype.DataType
+	if strings.TrimSpace(dataType) != "" {
+		parsed, err := datatype.Parse(dataType)
+		if err != nil {
+			i.fail("Component %s: pin %s.%s: %s", i.name, i.iface, name, err)
+			return i
+		}
+		t = parsed
+	}
+	return i.addPin(name, DirectedPinType{direction, SignalPin{t}})
+}
+
+func (i *InterfaceBuilder) addPin(name string, pin DirectedPinType) *InterfaceBuilder {
+	pins := i.leaf().Interfaces[i.iface].Pins
+	if _, ok := pins[name]; ok {
+		i.fail("Component %s: duplicate pin %s.%s", i.name, i.iface, name)
+		return i
+	}
+	pins[name] = pin
+	return i
+}
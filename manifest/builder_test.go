@@ -0,0 +1,129 @@
+package manifest
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestBuilder(t *testing.T) {
+	built, err := NewApp().
+		Component("x", "test.Component").
+		Configure("size", 1).
+		Interface("i").Publish("p", "string").Send("c", "string a, int b").Require().
+		Component("y", "test.Component").
+		Interface("i").Consume("p", "string").Pin("c", "receive-command(string a, int b)").
+		Bind("x", "y#i").
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := Parse(`
+        application:
+            components:
+                x:
+                    type: test.Component
+                    configuration:
+                        size: 1
+                    interfaces:
+                        i:
+                            p: publish-signal(string)
+                            c: send-command(string a, int b)
+                    required: [i]
+                y:
+                    type: test.Component
+                    interfaces:
+                        i:
+                            p: consume-signal(string)
+                            c: receive-command(string a, int b)
+            bindings:
+                - [x, y#i]
+    `)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(built, parsed) {
+		t.Errorf("\nBuilt:  %v\nParsed: %v", built, parsed)
+	}
+}
+
+func TestBuilderErrors(t *testing.T) {
+	cases := map[string]*AppBuilder{
+		"Duplicate component: x": NewApp().
+			Component("x", "test.Component").
+			Component("x", "test.Component").AppBuilder,
+		"Component x: duplicate interface i": NewApp().
+			Component("x", "test.Component").Interface("i").Interface("i").AppBuilder,
+		"Component x: duplicate pin i.p": NewApp().
+			Component("x", "test.Component").Interface("i").Publish("p", "int").Consume("p", "int").AppBuilder,
+		"Component x: pin i.p": NewApp().
+			Component("x", "test.Component").Interface("i").Publish("p", "list<").AppBuilder,
+		"Component x: pin i.q: Malformed data type in publish-signal(lst<string>)": NewApp().
+			Component("x", "test.Component").Interface("i").Pin("q", "publish-signal(lst<string>)").AppBuilder,
+		"Component x: pin i.c: Malformed data type in send-command(strin x)": NewApp().
+			Component("x", "test.Component").Interface("i").Send("c", "strin x").AppBuilder,
+		"Binding [x#j y]": NewApp().
+			Component("x", "test.Component").Interface("i").
+			Component("y", "test.Component").Interface("i").
+			Bind("x#j", "y"),
+		"Binding [x z]": NewApp().
+			Component("x", "test.Component").
+			Bind("x", "z"),
+	}
+	for expected, builder := range cases {
+		_, err := builder.Build()
+		if err == nil || !strings.HasPrefix(err.Error(), expected) {
+			t.Errorf("\nRaised: %v\nExpect: %v", err, expected)
+		}
+	}
+}
+
+func TestBuilderUntypedSignals(t *testing.T) {
+	built, err := NewApp().
+		Component("x", "test.Component").
+		Interface("i").Publish("p", "").Consume("q", " ").
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := Parse(`
+        application:
+            components:
+                x:
+                    type: test.Component
+                    interfaces:
+                        i:
+                            p: publish-signal()
+                            q: consume-signal()
+    `)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(built, parsed) {
+		t.Errorf("\nBuilt:  %v\nParsed: %v", built, parsed)
+	}
+}
+
+// applications built earlier don't see following calls of builder
+func TestBuilderReuse(t *testing.T) {
+	b := NewApp()
+	i := b.Component("x", "test.Component").Configure("a", 1).Interface("i").Publish("p", "string")
+	first, err := b.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	i.Publish("q", "int").Configure("b", 2).Interface("j")
+	b.Component("y", "test.Component").AppBuilder.Bind("x", "y")
+	second, err := b.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	x := first.Components["x"].(LeafComponent)
+	if len(first.Components) != 1 || len(first.Bindings) != 0 || len(x.Configuration) != 1 ||
+		len(x.Interfaces) != 1 || len(x.Interfaces["i"].Pins) != 1 {
+		t.Errorf("First application changed: %v", first)
+	}
+	if x := second.Components["x"].(LeafComponent); len(second.Components) != 2 || len(x.Interfaces["i"].Pins) != 2 || len(x.Configuration) != 2 {
+		t.Errorf("Unexpected second application: %v", second)
+	}
+}
@@ -0,0 +1,277 @@
+// Package catalog describes interface contracts of known component types
+// and validates leaf components against them. Descriptors are written in YAML:
+//
+//	types:
+//	    cobalt.common.Constants:
+//	        description: Publishes configured values as signals
+//	        interfaces:
+//	            result:
+//	                other-pins: [publish-signal]
+//	        other-interface-pins: [publish-signal]
+//	    test.Database:
+//	        configuration: [size]
+//	        other-interfaces: true
+//	        interfaces:
+//	            sql:
+//	                pins:
+//	                    url: publish-signal(string)
+//
+// pins listed in descriptor have to be declared with the same type, other
+// pins are allowed only of listed kinds, "*" allows pins of any kind;
+// other-interface-pins allows interfaces not listed with pins of given kinds
+package catalog
+
+import (
+	"errors"
+	"fmt"
+	"github.com/chemikadze/gonomi/manifest"
+	"github.com/chemikadze/gonomi/manifest/parsing"
+	"gopkg.in/yaml.v2"
+	"io/ioutil"
+	"sort"
+	"strings"
+)
+
+// component types by name
+type Catalog map[string]Descriptor
+
+type Descriptor struct {
+	Name        string
+	Description string
+	// configuration keys component has to set
+	Configuration []string
+	Interfaces    map[string]InterfaceDescriptor
+	// whether interfaces not listed in descriptor are allowed
+	OtherInterfaces bool
+	// kinds of pins allowed in interfaces not listed, any kind when empty;
+	// allows such interfaces by itself
+	OtherInterfacePins []string
+}
+
+type InterfaceDescriptor struct {
+	// component has to declare the interface
+	Mandatory bool
+	Pins      map[string]manifest.DirectedPinType
+	// kinds of pins allowed besides listed ones, e.g. publish-signal
+	OtherPins []string
+}
+
+const AnyPinKind = "*"
+
+type catalogRoot struct {
+	Types map[string]descriptor `yaml:"types"`
+}
+
+type descriptor struct {
+	Description        string                         `yaml:"description"`
+	Configuration      []string                       `yaml:"configuration"`
+	Interfaces         map[string]interfaceDescriptor `yaml:"interfaces"`
+	OtherInterfaces    bool                           `yaml:"other-interfaces"`
+	OtherInterfacePins []string                       `yaml:"other-interface-pins"`
+}
+
+type interfaceDescriptor struct {
+	Mandatory bool              `yaml:"mandatory"`
+	Pins      map[string]string `yaml:"pins"`
+	OtherPins []string          `yaml:"other-pins"`
+}
+
+var pinKinds = []string{"publish-signal", "consume-signal", "configuration", "send-command", "receive-command", AnyPinKind}
+
+// descriptors of core component types
+const builtin = `
+types:
+    cobalt.common.Constants:
+        description: Publishes configured values as signals
+        interfaces:
+            result:
+                other-pins: [publish-signal]
+        other-interface-pins: [publish-signal]
+    workflow.Instance:
+        description: Component driven by workflows in its configuration
+        configuration: [configuration.workflows]
+        other-interfaces: true
+    reference.Submodule:
+        description: Application launched as part of this one
+        configuration: [__locator.application-id]
+        other-interfaces: true
+    reference.Service:
+        description: Shared instance of other application
+        other-interfaces: true
+`
+
+func Builtin() Catalog {
+	c, err := Parse(builtin)
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+// parses descriptors written in YAML
+func Parse(src string) (Catalog, error) {
+	root := catalogRoot{}
+	if err := yaml.Unmarshal([]byte(src), &root); err != nil {
+		return nil, err
+	}
+	c := Catalog{}
+	for name, d := range root.Types {
+		for _, kind := range d.OtherInterfacePins {
+			if !contains(pinKinds, kind) {
+				return nil, parsing.ManifestError{fmt.Sprintf("Type %s: unknown pin kind %s", name, kind), 0, 0}
+			}
+		}
+		otherInterfaces := d.OtherInterfaces || len(d.OtherInterfacePins) > 0
+		result := Descriptor{name, d.Description, d.Configuration, map[string]InterfaceDescriptor{}, otherInterfaces, d.OtherInterfacePins}
+		for ifaceName, iface := range d.Interfaces {
+			pins := make(map[string]manifest.DirectedPinType)
+			for pin, repr := range iface.Pins {
+				pinType, err := manifest.ParsePinType(repr)
+				if err != nil {
+					return nil, parsing.ManifestError{fmt.Sprintf("Type %s: pin %s.%s: %s", name, ifaceName, pin, err), 0, 0}
+				}
+				pins[pin] = pinType
+			}
+			for _, kind := range iface.OtherPins {
+				if !contains(pinKinds, kind) {
+					return nil, parsing.ManifestError{fmt.Sprintf("Type %s: interface %s: unknown pin kind %s", name, ifaceName, kind), 0, 0}
+				}
+			}
+			result.Interfaces[ifaceName] = InterfaceDescriptor{iface.Mandatory, pins, iface.OtherPins}
+		}
+		c[name] = result
+	}
+	return c, nil
+}
+
+func Load(path string) (Catalog, error) {
+	src, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	c, err := Parse(string(src))
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("%s: %s", path, err))
+	}
+	return c, nil
+}
+
+// adds descriptors of other catalog, replacing ones with the same names
+func (c Catalog) Merge(other Catalog) {
+	for name, d := range other {
+		c[name] = d
+	}
+}
+
+// checks component against descriptor of its type,
+// components of unknown types are not checked
+func (c Catalog) Validate(leaf manifest.LeafComponent) []error {
+	d, ok := c[leaf.Type.Name]
+	if !ok {
+		return nil
+	}
+	return d.Validate(leaf)
+}
+
+func (d Descriptor) Validate(leaf manifest.LeafComponent) []error {
+	problems := []error{}
+	fail := func(format string, args ...interface{}) {
+		problems = append(problems, errors.New(fmt.Sprintf(format, args...)))
+	}
+	for _, key := range d.Configuration {
+		if _, ok := leaf.Configuration[key]; !ok {
+			fail("%s requires configuration %s", d.Name, key)
+		}
+	}
+	for _, name := range sortedKeys(d.Interfaces) {
+		if _, ok := leaf.Interfaces[name]; !ok && d.Interfaces[name].Mandatory {
+			fail("%s requires interface %s", d.Name, name)
+		}
+	}
+	for _, name := range sortedKeys(leaf.Interfaces) {
+		declared := leaf.Interfaces[name].Pins
+		iface, ok := d.Interfaces[name]
+		if !ok {
+			if !d.OtherInterfaces {
+				fail("%s has no interface %s", d.Name, name)
+				continue
+			}
+			if len(d.OtherInterfacePins) == 0 {
+				continue
+			}
+			iface = InterfaceDescriptor{OtherPins: d.OtherInterfacePins}
+		}
+		for _, pin := range sortedKeys(iface.Pins) {
+			pinType, ok := declared[pin]
+			if !ok {
+				fail("%s requires pin %s.%s: %s", d.Name, name, pin, iface.Pins[pin])
+			} else if pinType.String() != iface.Pins[pin].String() {
+				fail("pin %s.%s of %s has to be %s, not %s", name, pin, d.Name, iface.Pins[pin], pinType)
+			}
+		}
+		for _, pin := range sortedKeys(declared) {
+			if _, ok := iface.Pins[pin]; ok {
+				continue
+			}
+			if kind := PinKind(declared[pin]); !contains(iface.OtherPins, kind) && !contains(iface.OtherPins, AnyPinKind) {
+				fail("%s doesn't allow %s pin %s.%s", d.Name, kind, name, pin)
+			}
+		}
+	}
+	return problems
+}
+
+// validates every leaf component of application, problems are prefixed
+// with component path
+func (c Catalog) Check(app manifest.Application) []error {
+	problems := []error{}
+	manifest.Walk(app, manifest.VisitorFuncs{
+		OnEnterComponent: func(id manifest.ComponentId, component manifest.Component) manifest.WalkAction {
+			if leaf, ok := component.(manifest.LeafComponent); ok {
+				for _, problem := range c.Validate(leaf) {
+					problems = append(problems, errors.New(fmt.Sprintf("Component %s: %s", id, problem)))
+				}
+			}
+			return manifest.Continue
+		},
+	})
+	return problems
+}
+
+// kind of pin as written in manifest, e.g. publish-signal
+func PinKind(p manifest.DirectedPinType) string {
+	s := p.String()
+	if i := strings.Index(s, "("); i >= 0 {
+		return s[:i]
+	}
+	return s
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedKeys(m interface{}) []string {
+	keys := []string{}
+	switch m := m.(type) {
+	case map[string]InterfaceDescriptor:
+		for key := range m {
+			keys = append(keys, key)
+		}
+	case map[string]manifest.LeafInterface:
+		for key := range m {
+			keys = append(keys, key)
+		}
+	case map[string]manifest.DirectedPinType:
+		for key := range m {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,136 @@
+package catalog
+
+import (
+	"github.com/chemikadze/gonomi/manifest"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+)
+
+const databaseType = `
+types:
+    test.Database:
+        configuration: [size]
+        interfaces:
+            sql:
+                mandatory: true
+                pins:
+                    url: publish-signal(string)
+                other-pins: [receive-command]
+            monitoring:
+                other-pins: ["*"]
+`
+
+func parse(t *testing.T, src string) manifest.Application {
+	app, err := manifest.Parse(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return app
+}
+
+func messages(problems []error) []string {
+	result := []string{}
+	for _, problem := range problems {
+		result = append(result, problem.Error())
+	}
+	return result
+}
+
+func TestParse(t *testing.T) {
+	c, err := Parse(databaseType)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := c["test.Database"]
+	if d.Name != "test.Database" || !reflect.DeepEqual(d.Configuration, []string{"size"}) || d.OtherInterfaces {
+		t.Errorf("Unexpected descriptor: %v", d)
+	}
+	if pin := d.Interfaces["sql"].Pins["url"]; pin.String() != "publish-signal(string)" {
+		t.Errorf("Unexpected pin: %v", pin)
+	}
+	if _, err := Parse("types: {x: {interfaces: {i: {other-pins: [publish]}}}}"); err == nil || err.Error() != "Type x: interface i: unknown pin kind publish" {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if _, err := Parse("types: {x: {other-interface-pins: [signal]}}"); err == nil || err.Error() != "Type x: unknown pin kind signal" {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if _, err := Parse("types: {x: {interfaces: {i: {pins: {p: signal(string)}}}}}"); err == nil || err.Error() != "Type x: pin i.p: Unknown pin type: signal" {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	file, err := ioutil.TempFile("", "gonomi")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(file.Name())
+	file.WriteString(databaseType)
+	file.Close()
+	c, err := Load(file.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	catalog := Builtin()
+	catalog.Merge(c)
+	for _, name := range []string{"test.Database", "cobalt.common.Constants", "workflow.Instance", "reference.Submodule", "reference.Service"} {
+		if _, ok := catalog[name]; !ok {
+			t.Errorf("No descriptor for %s", name)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	c, err := Parse(databaseType)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Merge(Builtin())
+	app := parse(t, `
+application:
+    components:
+        db:
+            type: test.Database
+            interfaces:
+                sql:
+                    url: publish-signal(int)
+                    query: receive-command(string q)
+                    tables: publish-signal(list<string>)
+                monitoring:
+                    status: publish-signal(string)
+                admin:
+                    reset: receive-command()
+        empty:
+            type: test.Database
+            configuration:
+                size: 1
+        constants:
+            type: cobalt.common.Constants
+            interfaces:
+                result:
+                    port: publish-signal(int)
+                    host: consume-signal(string)
+                out:
+                    url: publish-signal(string)
+                    call: send-command(string x)
+        workflow:
+            type: workflow.Instance
+        other:
+            type: test.Unknown
+`)
+	expected := []string{
+		"Component constants: cobalt.common.Constants doesn't allow send-command pin out.call",
+		"Component constants: cobalt.common.Constants doesn't allow consume-signal pin result.host",
+		"Component db: test.Database requires configuration size",
+		"Component db: test.Database has no interface admin",
+		"Component db: pin sql.url of test.Database has to be publish-signal(string), not publish-signal(int)",
+		"Component db: test.Database doesn't allow publish-signal pin sql.tables",
+		"Component empty: test.Database requires interface sql",
+		"Component workflow: workflow.Instance requires configuration configuration.workflows",
+	}
+	if problems := messages(c.Check(app)); !reflect.DeepEqual(problems, expected) {
+		t.Errorf("\nProblems: %q\nExpect:   %q", problems, expected)
+	}
+}
@@ -0,0 +1,209 @@
+// Package compat tells whether components bound to interfaces of old
+// application version keep working with the new one.
+package compat
+
+import (
+	"github.com/chemikadze/gonomi/manifest"
+	"github.com/chemikadze/gonomi/manifest/datatype"
+	"github.com/chemikadze/gonomi/manifest/diff"
+	"strings"
+)
+
+// change classified as compatible or breaking
+type Result struct {
+	diff.Change
+	Breaking bool   `json:"breaking"`
+	Reason   string `json:"reason"`
+}
+
+func (r Result) String() string {
+	verdict := "compatible"
+	if r.Breaking {
+		verdict = "BREAKING"
+	}
+	return verdict + " " + r.Change.String() + " (" + r.Reason + ")"
+}
+
+// classifies every change between versions
+func Check(old, new manifest.Application) []Result {
+	changes := diff.Compare(old, new)
+	results := make([]Result, 0, len(changes))
+	for _, change := range changes {
+		breaking, reason := classify(old, new, change)
+		results = append(results, Result{change, breaking, reason})
+	}
+	return results
+}
+
+func HasBreaking(results []Result) bool {
+	for _, r := range results {
+		if r.Breaking {
+			return true
+		}
+	}
+	return false
+}
+
+func classify(old, new manifest.Application, change diff.Change) (bool, string) {
+	switch change.Object {
+	case diff.ComponentObject:
+		switch change.Kind {
+		case diff.Added:
+			return false, "new component"
+		case diff.Removed:
+			return true, "bindings to removed component break"
+		}
+		return false, "type changed, interfaces are compared separately"
+	case diff.InterfaceObject:
+		switch change.Kind {
+		case diff.Added:
+			if change.New == "required" {
+				return true, "new required interface has to be bound"
+			}
+			return false, "new optional interface"
+		case diff.Removed:
+			return true, "bindings to removed interface break"
+		}
+		if change.New == "required" {
+			return true, "interface became required"
+		}
+		return false, "interface became optional"
+	case diff.PinObject:
+		oldLeaf, oldIsLeaf := leaf(old, change.Component)
+		newLeaf, newIsLeaf := leaf(new, change.Component)
+		if !oldIsLeaf || !newIsLeaf {
+			return classifyReExport(old, new, change)
+		}
+		switch change.Kind {
+		case diff.Added:
+			if newLeaf.Interfaces[change.Interface].Required {
+				return true, "bound component has to provide new pin of required interface"
+			}
+			return false, "new pin"
+		case diff.Removed:
+			return true, "removed pin can't be bound"
+		}
+		oldPin := oldLeaf.Interfaces[change.Interface].Pins[change.Pin]
+		newPin := newLeaf.Interfaces[change.Interface].Pins[change.Pin]
+		return classifyPin(oldPin, newPin)
+	}
+	return false, "not a part of interface"
+}
+
+// pins of composite interfaces are compared by pins they resolve to,
+// which is what components bound to the composite see
+func classifyReExport(old, new manifest.Application, change diff.Change) (bool, string) {
+	switch change.Kind {
+	case diff.Added:
+		return false, "new pin"
+	case diff.Removed:
+		return true, "removed pin can't be bound"
+	}
+	id := componentId(change.Component)
+	pin := manifest.PinId{change.Interface, change.Pin}
+	oldPin, err := old.LookupPin(id, pin)
+	if err != nil {
+		return false, "old re-export didn't resolve to a pin"
+	}
+	newPin, err := new.LookupPin(id, pin)
+	if err != nil {
+		return true, "re-export no longer resolves to a pin"
+	}
+	return classifyPin(oldPin, newPin)
+}
+
+// pin of the component sees its own side: it produces values of published signals
+// and sent command arguments, and consumes the rest
+func classifyPin(old, new manifest.DirectedPinType) (bool, string) {
+	if old.Direction != new.Direction || old.PinType.PinTypeName() != new.PinType.PinTypeName() {
+		return true, "pin kind changed"
+	}
+	switch oldType := old.PinType.(type) {
+	case manifest.SignalPin:
+		newType := new.PinType.(manifest.SignalPin)
+		if old.Direction.IsSend() {
+			if !assignable(newType.DataType, oldType.DataType) {
+				return true, "published values no longer match consumers' type"
+			}
+		} else if !assignable(oldType.DataType, newType.DataType) {
+			return true, "values of old type are no longer accepted"
+		}
+	case manifest.ConfigurationPin:
+		if !assignable(new.PinType.(manifest.ConfigurationPin).DataType, oldType.DataType) {
+			return true, "configuration values no longer match consumers' type"
+		}
+	case manifest.CommandPin:
+		newType := new.PinType.(manifest.CommandPin)
+		produced := [][2]datatype.DataType{}
+		consumed := [][2]datatype.DataType{}
+		if old.Direction.IsSend() {
+			produced = append(produced, [2]datatype.DataType{oldType.Arguments, newType.Arguments})
+			consumed = append(consumed, [2]datatype.DataType{oldType.Progress, newType.Progress}, [2]datatype.DataType{oldType.Result, newType.Result})
+		} else {
+			consumed = append(consumed, [2]datatype.DataType{oldType.Arguments, newType.Arguments})
+			produced = append(produced, [2]datatype.DataType{oldType.Progress, newType.Progress}, [2]datatype.DataType{oldType.Result, newType.Result})
+		}
+		for _, p := range produced {
+			if !assignable(p[1], p[0]) {
+				return true, "produced command values no longer match counterpart's type"
+			}
+		}
+		for _, c := range consumed {
+			if !assignable(c[0], c[1]) {
+				return true, "command values of old type are no longer accepted"
+			}
+		}
+	}
+	return false, "old values are still accepted"
+}
+
+// tells whether every value of from type can be used where to type is expected,
+// records may carry extra fields
+func assignable(from, to datatype.DataType) bool {
+	if to == nil {
+		return true
+	}
+	if from == nil {
+		return false
+	}
+	switch to := to.(type) {
+	case datatype.List:
+		from, ok := from.(datatype.List)
+		return ok && assignable(from.ElementDataType, to.ElementDataType)
+	case datatype.Map:
+		from, ok := from.(datatype.Map)
+		return ok && assignable(from.KeyDataType, to.KeyDataType) && assignable(to.KeyDataType, from.KeyDataType) &&
+			assignable(from.ValueDataType, to.ValueDataType)
+	case datatype.Record:
+		from, ok := from.(datatype.Record)
+		if !ok {
+			return false
+		}
+		for name, fieldType := range to.Fields {
+			fromType, ok := from.Fields[name]
+			if !ok || !assignable(fromType, fieldType) {
+				return false
+			}
+		}
+		return true
+	}
+	return from.DataTypeName() == to.DataTypeName()
+}
+
+// dotted path of component, empty one is application itself
+func componentId(path string) manifest.ComponentId {
+	if path == "" {
+		return manifest.ComponentId{}
+	}
+	return manifest.ComponentId{strings.Split(path, ".")}
+}
+
+// finds leaf component by dotted path
+func leaf(app manifest.Application, path string) (manifest.LeafComponent, bool) {
+	c, err := app.Lookup(componentId(path))
+	if err != nil {
+		return manifest.LeafComponent{}, false
+	}
+	leaf, ok := c.(manifest.LeafComponent)
+	return leaf, ok
+}
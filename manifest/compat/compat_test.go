@@ -0,0 +1,106 @@
+package compat
+
+import (
+	"github.com/chemikadze/gonomi/manifest"
+	"testing"
+)
+
+func app(t *testing.T, pins string, required string) manifest.Application {
+	app, err := manifest.Parse(`
+        application:
+            components:
+                x:
+                    type: test.Component
+                    interfaces:
+                        i:
+` + pins + `
+                    required: [` + required + `]
+    `)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return app
+}
+
+type compatCase struct {
+	Old, New string
+	Breaking bool
+}
+
+func TestPinChanges(t *testing.T) {
+	cases := []compatCase{
+		{"p: publish-signal(record<int a, int b>)", "p: publish-signal(record<int a>)", true},
+		{"p: publish-signal(record<int a>)", "p: publish-signal(record<int a, int b>)", false},
+		{"p: publish-signal(string)", "p: publish-signal(int)", true},
+		{"p: consume-signal(record<int a>)", "p: consume-signal(record<int a, int b>)", true},
+		{"p: consume-signal(record<int a, int b>)", "p: consume-signal(record<int a>)", false},
+		{"p: receive-command(int a)", "p: receive-command(int a, int b)", true},
+		{"p: receive-command(int a, int b)", "p: receive-command(int a)", false},
+		{"p: send-command(int a)", "p: send-command(int a, int b)", false},
+		{"p: send-command(int a, int b)", "p: send-command(int a)", true},
+		{"p: publish-signal(list<record<int a>>)", "p: publish-signal(list<record<int a, int b>>)", false},
+		{"p: publish-signal(string)", "p: consume-signal(string)", true},
+		{"p: publish-signal(string)", "q: publish-signal(string)", true},
+		{"p: publish-signal(string)", "p: publish-signal(string)\n                            q: consume-signal(string)", false},
+	}
+	for _, c := range cases {
+		results := Check(app(t, "                            "+c.Old, ""), app(t, "                            "+c.New, ""))
+		if len(results) == 0 {
+			t.Errorf("%s -> %s: changes expected", c.Old, c.New)
+		}
+		if HasBreaking(results) != c.Breaking {
+			t.Errorf("%s -> %s: expected breaking=%v, got %v", c.Old, c.New, c.Breaking, results)
+		}
+	}
+}
+
+func TestRequiredInterface(t *testing.T) {
+	pins := "                            p: publish-signal(string)"
+	if !HasBreaking(Check(app(t, pins, ""), app(t, pins, "i"))) {
+		t.Error("Making interface required should be breaking")
+	}
+	if HasBreaking(Check(app(t, pins, "i"), app(t, pins, ""))) {
+		t.Error("Making interface optional should be compatible")
+	}
+	more := pins + "\n                            q: consume-signal(string)"
+	if !HasBreaking(Check(app(t, pins, "i"), app(t, more, "i"))) {
+		t.Error("Adding pin to required interface should be breaking")
+	}
+}
+
+func exporting(t *testing.T, target string) manifest.Application {
+	app, err := manifest.Parse(`
+        application:
+            interfaces:
+                api:
+                    url: bind(x#i.` + target + `)
+            components:
+                x:
+                    type: test.Component
+                    interfaces:
+                        i:
+                            a: publish-signal(string)
+                            b: publish-signal(int)
+                            c: publish-signal(string)
+                            d: consume-signal(string)
+    `)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return app
+}
+
+// manifests bind to application interface, so re-pointed exports are
+// compared by pins they resolve to
+func TestReExports(t *testing.T) {
+	cases := map[string]bool{"b": true, "c": false, "d": true, "missing": true}
+	for target, breaking := range cases {
+		results := Check(exporting(t, "a"), exporting(t, target))
+		if len(results) != 1 {
+			t.Fatalf("%s: one change expected, got %v", target, results)
+		}
+		if results[0].Breaking != breaking {
+			t.Errorf("%s: expected breaking=%v, got %v", target, breaking, results[0])
+		}
+	}
+}
@@ -0,0 +1,138 @@
+// Package constants gives typed view of cobalt.common.Constants components,
+// which publish values set in configuration by interface.pin key:
+//
+//	constants:
+//	    type: cobalt.common.Constants
+//	    configuration:
+//	        result.port: 80
+//	        result.hosts: [a.example.com, b.example.com]
+//	    interfaces:
+//	        result:
+//	            port: publish-signal(int)
+//	            hosts: publish-signal(list<string>)
+package constants
+
+import (
+	"errors"
+	"fmt"
+	"github.com/chemikadze/gonomi/manifest"
+	"github.com/chemikadze/gonomi/manifest/datatype"
+	"sort"
+	"strings"
+)
+
+const TypeName = "cobalt.common.Constants"
+
+// value published by pin
+type Value struct {
+	Pin      manifest.PinId
+	DataType datatype.DataType
+	Value    interface{}
+}
+
+type Constants struct {
+	// sorted by interface and pin
+	Values []Value
+}
+
+// gives typed view of constants component, false is returned for
+// components of other types; pins without values and configuration keys
+// without pins are skipped, error describes first value which doesn't
+// match data type of its pin
+func Parse(c manifest.LeafComponent) (Constants, bool, error) {
+	if c.Type.Name != TypeName {
+		return Constants{}, false, nil
+	}
+	constants, problems := parse(c)
+	for _, problem := range problems {
+		if _, ok := problem.(valueError); ok {
+			return Constants{}, true, problem
+		}
+	}
+	return constants, true, nil
+}
+
+// value of publish-signal pin not matching its data type
+type valueError struct {
+	error
+}
+
+// checks every constants component of application: all pins are
+// publish-signal ones, each has a value and the value matches its data type
+func Check(app manifest.Application) []error {
+	problems := []error{}
+	manifest.Walk(app, manifest.VisitorFuncs{
+		OnEnterComponent: func(id manifest.ComponentId, c manifest.Component) manifest.WalkAction {
+			if leaf, ok := c.(manifest.LeafComponent); ok && leaf.Type.Name == TypeName {
+				for _, problem := range Validate(leaf) {
+					problems = append(problems, errors.New(fmt.Sprintf("Component %s: %s", id, problem)))
+				}
+			}
+			return manifest.Continue
+		},
+	})
+	return problems
+}
+
+// problems of single constants component
+func Validate(c manifest.LeafComponent) []error {
+	_, problems := parse(c)
+	return problems
+}
+
+func parse(c manifest.LeafComponent) (Constants, []error) {
+	constants := Constants{}
+	problems := []error{}
+	for _, name := range sortedKeys(c.Interfaces) {
+		pins := c.Interfaces[name].Pins
+		for _, pin := range sortedKeys(pins) {
+			key := name + "." + pin
+			signal, ok := pins[pin].PinType.(manifest.SignalPin)
+			if !ok || !pins[pin].Direction.IsSend() {
+				problems = append(problems, errors.New(fmt.Sprintf("Constant %s: %s is not a publish-signal pin", key, pins[pin])))
+				continue
+			}
+			value, ok := c.Configuration[key]
+			if !ok {
+				problems = append(problems, errors.New(fmt.Sprintf("Constant %s: no value in configuration", key)))
+				continue
+			}
+			if err := datatype.Check(signal.DataType, value); err != nil {
+				problems = append(problems, valueError{errors.New(fmt.Sprintf("Constant %s: %s", key, err))})
+				continue
+			}
+			constants.Values = append(constants.Values, Value{manifest.PinId{name, pin}, signal.DataType, value})
+		}
+	}
+	for _, key := range sortedKeys(c.Configuration) {
+		dot := strings.LastIndex(key, ".")
+		if dot < 0 {
+			problems = append(problems, errors.New(fmt.Sprintf("Configuration %s: expected interface.pin key", key)))
+			continue
+		}
+		if _, ok := c.Interfaces[key[:dot]].Pins[key[dot+1:]]; !ok {
+			problems = append(problems, errors.New(fmt.Sprintf("Configuration %s: no pin to publish value", key)))
+		}
+	}
+	return constants, problems
+}
+
+func sortedKeys(m interface{}) []string {
+	keys := []string{}
+	switch m := m.(type) {
+	case map[string]manifest.LeafInterface:
+		for key := range m {
+			keys = append(keys, key)
+		}
+	case map[string]manifest.DirectedPinType:
+		for key := range m {
+			keys = append(keys, key)
+		}
+	case manifest.Configuration:
+		for key := range m {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
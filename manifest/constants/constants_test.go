@@ -0,0 +1,90 @@
+package constants
+
+import (
+	"github.com/chemikadze/gonomi/manifest"
+	"github.com/chemikadze/gonomi/manifest/datatype"
+	"reflect"
+	"testing"
+)
+
+func parseApp(t *testing.T, src string) manifest.Application {
+	app, err := manifest.Parse(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return app
+}
+
+func TestParse(t *testing.T) {
+	app := parseApp(t, `
+application:
+    components:
+        constants:
+            type: cobalt.common.Constants
+            configuration:
+                result.port: 80
+                result.hosts: [a, b]
+            interfaces:
+                result:
+                    port: publish-signal(int)
+                    hosts: publish-signal(list<string>)
+`)
+	c, ok, err := Parse(app.Components["constants"].(manifest.LeafComponent))
+	if !ok || err != nil {
+		t.Fatal(ok, err)
+	}
+	expected := []Value{
+		{manifest.PinId{"result", "hosts"}, datatype.List{datatype.String{}}, []interface{}{"a", "b"}},
+		{manifest.PinId{"result", "port"}, datatype.Int{}, 80},
+	}
+	if !reflect.DeepEqual(c.Values, expected) {
+		t.Errorf("\nValues: %v\nExpect: %v", c.Values, expected)
+	}
+	leaf := app.Components["constants"].(manifest.LeafComponent)
+	leaf.Configuration["result.extra"] = 1
+	leaf.Interfaces["result"].Pins["name"], _ = manifest.ParsePinType("publish-signal(string)")
+	if c, _, err := Parse(leaf); err != nil || !reflect.DeepEqual(c.Values, expected) {
+		t.Errorf("Keys without pins and pins without values should be skipped: %v %v", c.Values, err)
+	}
+	if _, ok, _ := Parse(manifest.LeafComponent{manifest.Type{"test.Component"}, nil, nil}); ok {
+		t.Error("Only constants should be recognized")
+	}
+}
+
+func TestCheck(t *testing.T) {
+	app := parseApp(t, `
+application:
+    components:
+        constants:
+            type: cobalt.common.Constants
+            configuration:
+                result.port: "80"
+                result.host: localhost
+                result.extra: 1
+                timeout: 10
+            interfaces:
+                result:
+                    port: publish-signal(int)
+                    host: publish-signal(string)
+                    name: publish-signal(string)
+                    input: consume-signal(string)
+        other:
+            type: test.Component
+            configuration:
+                timeout: 10
+`)
+	expected := []string{
+		"Component constants: Constant result.input: consume-signal(string) is not a publish-signal pin",
+		"Component constants: Constant result.name: no value in configuration",
+		"Component constants: Constant result.port: Expected int, got string",
+		"Component constants: Configuration result.extra: no pin to publish value",
+		"Component constants: Configuration timeout: expected interface.pin key",
+	}
+	problems := []string{}
+	for _, problem := range Check(app) {
+		problems = append(problems, problem.Error())
+	}
+	if !reflect.DeepEqual(problems, expected) {
+		t.Errorf("\nProblems: %q\nExpect:   %q", problems, expected)
+	}
+}
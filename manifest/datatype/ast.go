@@ -1,6 +1,7 @@
 package datatype
 
 import (
+	"sort"
 	"strings"
 )
 
@@ -49,9 +50,19 @@ type Record struct {
 }
 
 func (r Record) DataTypeName() string {
-	items := make([]string, 0, 5)
-	for key, value := range r.Fields {
-		items = append(items, value.DataTypeName()+" "+key)
+	return "record<" + r.BodyName() + ">"
+}
+
+// fields without enclosing brackets, sorted by name
+func (r Record) BodyName() string {
+	keys := make([]string, 0, len(r.Fields))
+	for key := range r.Fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	items := make([]string, 0, len(keys))
+	for _, key := range keys {
+		items = append(items, r.Fields[key].DataTypeName()+" "+key)
 	}
-	return "record<" + strings.Join(items, ", ") + ">"
+	return strings.Join(items, ", ")
 }
@@ -0,0 +1,105 @@
+package datatype
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// checks that Go value can be carried by data type,
+// nil data type stands for untyped pin and accepts anything
+func Check(t DataType, value interface{}) error {
+	return CheckSkipping(t, value, func(interface{}) bool { return false })
+}
+
+// checks like Check does, but values for which skip is true are accepted
+// by any data type, e.g. placeholders which are only known later
+func CheckSkipping(t DataType, value interface{}, skip func(value interface{}) bool) error {
+	if t == nil || skip(value) {
+		return nil
+	}
+	switch t := t.(type) {
+	case String:
+		if _, ok := value.(string); !ok {
+			return mismatch(t, value)
+		}
+	case Bool:
+		if _, ok := value.(bool); !ok {
+			return mismatch(t, value)
+		}
+	case Int:
+		switch value.(type) {
+		case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		default:
+			return mismatch(t, value)
+		}
+	case List:
+		v := reflect.ValueOf(value)
+		if value == nil || (v.Kind() != reflect.Slice && v.Kind() != reflect.Array) {
+			return mismatch(t, value)
+		}
+		for i := 0; i < v.Len(); i++ {
+			if err := CheckSkipping(t.ElementDataType, v.Index(i).Interface(), skip); err != nil {
+				return errors.New(fmt.Sprintf("[%d]: %s", i, err))
+			}
+		}
+	case Map:
+		v := reflect.ValueOf(value)
+		if value == nil || v.Kind() != reflect.Map {
+			return mismatch(t, value)
+		}
+		for _, key := range v.MapKeys() {
+			if err := CheckSkipping(t.KeyDataType, key.Interface(), skip); err != nil {
+				return errors.New(fmt.Sprintf("key %v: %s", key.Interface(), err))
+			}
+			if err := CheckSkipping(t.ValueDataType, v.MapIndex(key).Interface(), skip); err != nil {
+				return errors.New(fmt.Sprintf("[%v]: %s", key.Interface(), err))
+			}
+		}
+	case Record:
+		fields, ok := recordFields(value)
+		if !ok {
+			return mismatch(t, value)
+		}
+		for name, fieldType := range t.Fields {
+			fieldValue, ok := fields[name]
+			if !ok {
+				return errors.New(fmt.Sprintf("Missing record field: %s", name))
+			}
+			if err := CheckSkipping(fieldType, fieldValue, skip); err != nil {
+				return errors.New(fmt.Sprintf("%s: %s", name, err))
+			}
+		}
+		for name := range fields {
+			if _, ok := t.Fields[name]; !ok {
+				return errors.New(fmt.Sprintf("Unknown record field: %s", name))
+			}
+		}
+	default:
+		return errors.New(fmt.Sprintf("Unknown type %s", t.DataTypeName()))
+	}
+	return nil
+}
+
+func mismatch(t DataType, value interface{}) error {
+	return errors.New(fmt.Sprintf("Expected %s, got %T", t.DataTypeName(), value))
+}
+
+// records come either from Go code or from yaml decoder
+func recordFields(value interface{}) (map[string]interface{}, bool) {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		return value, true
+	case map[interface{}]interface{}:
+		fields := make(map[string]interface{}, len(value))
+		for k, v := range value {
+			name, ok := k.(string)
+			if !ok {
+				return nil, false
+			}
+			fields[name] = v
+		}
+		return fields, true
+	}
+	return nil, false
+}
@@ -0,0 +1,60 @@
+package datatype
+
+import (
+	"testing"
+)
+
+type valueCase struct {
+	DataType DataType
+	Value    interface{}
+}
+
+func TestCheckValid(t *testing.T) {
+	cases := []valueCase{
+		valueCase{String{}, "a"},
+		valueCase{Int{}, 1},
+		valueCase{Int{}, int64(1)},
+		valueCase{Bool{}, true},
+		valueCase{List{Int{}}, []interface{}{1, 2}},
+		valueCase{List{String{}}, []string{"a"}},
+		valueCase{Map{String{}, Int{}}, map[interface{}]interface{}{"a": 1}},
+		valueCase{Record{map[string]DataType{"a": Int{}}}, map[string]interface{}{"a": 1}},
+		valueCase{Record{}, map[string]interface{}{}},
+		valueCase{nil, 42},
+	}
+	for _, c := range cases {
+		if err := Check(c.DataType, c.Value); err != nil {
+			t.Errorf("%v should accept %v: %s", c.DataType, c.Value, err)
+		}
+	}
+}
+
+func TestCheckInvalid(t *testing.T) {
+	cases := []valueCase{
+		valueCase{String{}, 1},
+		valueCase{Int{}, "1"},
+		valueCase{Int{}, 1.5},
+		valueCase{Bool{}, nil},
+		valueCase{List{Int{}}, []interface{}{1, "2"}},
+		valueCase{List{Int{}}, 1},
+		valueCase{Map{String{}, Int{}}, map[interface{}]interface{}{1: 1}},
+		valueCase{Record{map[string]DataType{"a": Int{}}}, map[string]interface{}{}},
+		valueCase{Record{map[string]DataType{"a": Int{}}}, map[string]interface{}{"a": 1, "b": 2}},
+	}
+	for _, c := range cases {
+		if err := Check(c.DataType, c.Value); err == nil {
+			t.Errorf("%v should reject %v", c.DataType, c.Value)
+		}
+	}
+}
+
+func TestCheckSkipping(t *testing.T) {
+	placeholder := func(value interface{}) bool { return value == "?" }
+	if err := CheckSkipping(List{Int{}}, []interface{}{1, "?"}, placeholder); err != nil {
+		t.Error("Placeholder should be accepted:", err)
+	}
+	err := CheckSkipping(List{Int{}}, []interface{}{1, "?", "x"}, placeholder)
+	if err == nil || err.Error() != "[2]: Expected int, got string" {
+		t.Error("Literal should be checked, got", err)
+	}
+}
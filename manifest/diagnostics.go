@@ -0,0 +1,216 @@
+package manifest
+
+import (
+	"fmt"
+	"github.com/chemikadze/gonomi/manifest/parsing"
+	yamlv2 "gopkg.in/yaml.v2"
+	"gopkg.in/yaml.v3"
+	"strconv"
+	"strings"
+)
+
+type ParseOptions struct {
+	// every ignored or unknown element fails parsing
+	Strict bool
+}
+
+// parses manifest like Parse does and reports elements which parser ignores:
+// unknown keys, non-string keys, pins and required entries; in strict mode
+// they are errors and returned together as parsing.Diagnostics;
+// elements parser rejects, e.g. malformed pin types, are reported as errors
+// together with parser error
+func ParseWithDiagnostics(manifest string, options ParseOptions) (Application, []parsing.Diagnostic, error) {
+	c := checker{}
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(manifest), &doc); err != nil {
+		diagnostic := yamlDiagnostic(err)
+		c.diagnostics = append(c.diagnostics, diagnostic)
+		// decoder gives no nodes on error, so lines before the error are checked
+		if diagnostic.Line > 1 {
+			lines := strings.SplitAfter(manifest, "\n")
+			yaml.Unmarshal([]byte(strings.Join(lines[:diagnostic.Line-1], "")), &doc)
+		}
+	}
+	if len(doc.Content) != 0 {
+		c.root(doc.Content[0])
+	}
+	app, err := Parse(manifest)
+	if err != nil {
+		return Application{}, c.diagnostics, err
+	}
+	if options.Strict && len(c.diagnostics) != 0 {
+		for i := range c.diagnostics {
+			c.diagnostics[i].Severity = parsing.Error
+		}
+		return Application{}, c.diagnostics, parsing.Diagnostics(c.diagnostics)
+	}
+	return app, c.diagnostics, nil
+}
+
+type checker struct {
+	diagnostics []parsing.Diagnostic
+}
+
+func (c *checker) report(node *yaml.Node, format string, args ...interface{}) {
+	c.diagnostics = append(c.diagnostics, parsing.Diagnostic{parsing.Warning, fmt.Sprintf(format, args...), node.Line, node.Column})
+}
+
+// element parser fails on
+func (c *checker) fail(node *yaml.Node, format string, args ...interface{}) {
+	c.diagnostics = append(c.diagnostics, parsing.Diagnostic{parsing.Error, fmt.Sprintf(format, args...), node.Line, node.Column})
+}
+
+// decoder error with line taken from its message
+func yamlDiagnostic(err error) parsing.Diagnostic {
+	message, line := err.Error(), 0
+	if match := yamlErrorLine.FindStringSubmatch(message); match != nil {
+		line, _ = strconv.Atoi(match[1])
+		message = match[2]
+	} else {
+		message = strings.TrimPrefix(message, "yaml: ")
+	}
+	return parsing.Diagnostic{parsing.Error, message, line, 0}
+}
+
+func (c *checker) root(node *yaml.Node) {
+	c.mapping(node, "Manifest", func(key *yaml.Node, value *yaml.Node) {
+		switch key.Value {
+		case "application":
+			c.application(value)
+		case "imports":
+		case "services":
+			c.section(value, "Services", "Service", "bindings")
+		case "policies":
+			c.section(value, "Policies", "Policy", "components")
+		default:
+			c.report(key, "Unknown key: %s", key.Value)
+		}
+	})
+}
+
+func (c *checker) application(node *yaml.Node) {
+	c.mapping(node, "Application", func(key *yaml.Node, value *yaml.Node) {
+		switch key.Value {
+		case "components":
+			c.mapping(value, "Application", func(key *yaml.Node, value *yaml.Node) {
+				c.component(key.Value, value)
+			})
+		case "interfaces":
+			c.mapping(value, "Application", func(key *yaml.Node, value *yaml.Node) {
+				iface := key.Value
+				c.mapping(value, "Application: interface "+iface, func(key *yaml.Node, value *yaml.Node) {
+					if !isString(value) {
+						c.report(value, "Application: pin %s.%s: non-string pin binding is ignored", iface, key.Value)
+					} else if _, err := ParsePinBinding(value.Value); err != nil {
+						c.fail(value, "Application: pin %s.%s: %s", iface, key.Value, err)
+					}
+				})
+			})
+		case "bindings":
+		default:
+			c.report(key, "Application: unknown key %s", key.Value)
+		}
+	})
+}
+
+func (c *checker) component(name string, node *yaml.Node) {
+	context := "Component " + name
+	declared := map[string]bool{}
+	var required *yaml.Node
+	c.mapping(node, context, func(key *yaml.Node, value *yaml.Node) {
+		switch key.Value {
+		case "type":
+		case "configuration":
+			c.mapping(value, context+": configuration", func(*yaml.Node, *yaml.Node) {})
+		case "interfaces":
+			c.mapping(value, context, func(key *yaml.Node, value *yaml.Node) {
+				declared[key.Value] = true
+				c.leafInterface(context, key.Value, value)
+			})
+		case "required":
+			required = value
+		case "bindings":
+			c.report(key, "%s: bindings of leaf component are ignored", context)
+		default:
+			c.report(key, "%s: unknown key %s", context, key.Value)
+		}
+	})
+	if required = resolveAlias(required); required == nil || required.Kind != yaml.SequenceNode {
+		return
+	}
+	for _, entry := range required.Content {
+		entry = resolveAlias(entry)
+		if !isString(entry) {
+			c.report(entry, "%s: non-string required entry %s is ignored", context, entry.Value)
+		} else if !declared[entry.Value] {
+			c.report(entry, "%s: required interface %s is not declared", context, entry.Value)
+		}
+	}
+}
+
+// services and policies, targets is the key listing components
+func (c *checker) section(node *yaml.Node, name string, kind string, targets string) {
+	c.mapping(node, name, func(key *yaml.Node, value *yaml.Node) {
+		context := kind + " " + key.Value
+		c.mapping(value, context, func(key *yaml.Node, value *yaml.Node) {
+			switch key.Value {
+			case "type", targets:
+			case "configuration":
+				c.mapping(value, context+": configuration", func(*yaml.Node, *yaml.Node) {})
+			default:
+				c.report(key, "%s: unknown key %s", context, key.Value)
+			}
+		})
+	})
+}
+
+func (c *checker) leafInterface(context string, name string, node *yaml.Node) {
+	c.mapping(node, context+": interface "+name, func(key *yaml.Node, value *yaml.Node) {
+		if !isString(value) {
+			c.report(value, "%s: pin %s.%s: non-string pin type is ignored", context, name, key.Value)
+		} else if _, err := ParsePinType(value.Value); err != nil {
+			c.fail(value, "%s: pin %s.%s: %s", context, name, key.Value, err)
+		}
+	})
+}
+
+// calls f for every string key of mapping, others are reported;
+// nodes of other kinds are left to the parser
+func (c *checker) mapping(node *yaml.Node, context string, f func(key *yaml.Node, value *yaml.Node)) {
+	node = resolveAlias(node)
+	if node == nil || node.Kind != yaml.MappingNode {
+		return
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := resolveAlias(node.Content[i]), resolveAlias(node.Content[i+1])
+		if !isString(key) {
+			c.report(key, "%s: non-string key %s is ignored", context, key.Value)
+			continue
+		}
+		f(key, value)
+	}
+}
+
+func resolveAlias(node *yaml.Node) *yaml.Node {
+	for node != nil && node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	return node
+}
+
+// tells whether parser sees scalar as string, it resolves plain
+// scalars like yes or on to booleans unlike yaml.v3
+func isString(node *yaml.Node) bool {
+	if node.Kind != yaml.ScalarNode || node.Tag != "!!str" {
+		return false
+	}
+	if node.Style&(yaml.TaggedStyle|yaml.DoubleQuotedStyle|yaml.SingleQuotedStyle|yaml.LiteralStyle|yaml.FoldedStyle) != 0 {
+		return true
+	}
+	var value interface{}
+	if err := yamlv2.Unmarshal([]byte(node.Value), &value); err != nil {
+		return false
+	}
+	_, ok := value.(string)
+	return ok
+}
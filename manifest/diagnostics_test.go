@@ -0,0 +1,96 @@
+package manifest
+
+import (
+	"github.com/chemikadze/gonomi/manifest/parsing"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const sloppyManifest = `application:
+    components:
+        x:
+            type: test.Component
+            interfacs:
+                i:
+                    p: publish-signal(string)
+            interfaces:
+                i:
+                    p: publish-signal(string)
+                    q: 1
+                1: {}
+            required: [i, j, yes]
+    bindings: []
+`
+
+func TestParseWithDiagnostics(t *testing.T) {
+	app, diagnostics, err := ParseWithDiagnostics(sloppyManifest, ParseOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, _ := Parse(sloppyManifest)
+	if !reflect.DeepEqual(app, parsed) {
+		t.Errorf("\nParsed: %v\nExpect: %v", app, parsed)
+	}
+	expected := []parsing.Diagnostic{
+		{parsing.Warning, "Component x: unknown key interfacs", 5, 13},
+		{parsing.Warning, "Component x: pin i.q: non-string pin type is ignored", 11, 24},
+		{parsing.Warning, "Component x: non-string key 1 is ignored", 12, 17},
+		{parsing.Warning, "Component x: required interface j is not declared", 13, 27},
+		{parsing.Warning, "Component x: non-string required entry yes is ignored", 13, 30},
+	}
+	if !reflect.DeepEqual(diagnostics, expected) {
+		t.Errorf("\nReported: %v\nExpect:   %v", diagnostics, expected)
+	}
+}
+
+func TestParseStrict(t *testing.T) {
+	app, diagnostics, err := ParseWithDiagnostics(sloppyManifest, ParseOptions{Strict: true})
+	if err == nil {
+		t.Fatal("Error expected")
+	}
+	if (!app.Equal(Application{})) {
+		t.Error("Failed parsing should yield empty application, got:", app)
+	}
+	if len(diagnostics) != 5 || diagnostics[0].Severity != parsing.Error {
+		t.Error("Errors expected, got", diagnostics)
+	}
+	if !strings.HasPrefix(err.Error(), "5:13: error: Component x: unknown key interfacs\n") {
+		t.Error("Unexpected error:", err)
+	}
+	_, diagnostics, err = ParseWithDiagnostics(`
+        application:
+            components:
+                x:
+                    type: test.Component
+                    required: ["i"]
+                    interfaces: {"i": {"p": publish-signal(string)}}
+    `, ParseOptions{Strict: true})
+	if err != nil || len(diagnostics) != 0 {
+		t.Error("Clean manifest should pass, got", err)
+	}
+}
+
+func TestParseWithDiagnosticsErrors(t *testing.T) {
+	_, diagnostics, err := ParseWithDiagnostics(`application:
+    components:
+        x:
+            typ: test.Component
+            interfaces:
+                i:
+                    p: publish-signal(lst<string>)
+                    q: publish-signal(string)
+    bindings: [[x, y]
+`, ParseOptions{})
+	if err == nil {
+		t.Fatal("Error expected")
+	}
+	expected := []parsing.Diagnostic{
+		{parsing.Error, "did not find expected ',' or ']'", 8, 0},
+		{parsing.Warning, "Component x: unknown key typ", 4, 13},
+		{parsing.Error, "Component x: pin i.p: Malformed data type in publish-signal(lst<string>): Unknown type lst", 7, 24},
+	}
+	if !reflect.DeepEqual(diagnostics, expected) {
+		t.Errorf("\nReported: %v\nExpect:   %v", diagnostics, expected)
+	}
+}
@@ -0,0 +1,301 @@
+// Package diff compares component models of two applications.
+package diff
+
+import (
+	"fmt"
+	"github.com/chemikadze/gonomi/manifest"
+	"github.com/chemikadze/gonomi/manifest/datatype"
+	"reflect"
+	"sort"
+)
+
+type ChangeKind string
+
+const (
+	Added   ChangeKind = "added"
+	Removed ChangeKind = "removed"
+	Changed ChangeKind = "changed"
+)
+
+type ObjectKind string
+
+const (
+	ComponentObject     ObjectKind = "component"
+	InterfaceObject     ObjectKind = "interface"
+	PinObject           ObjectKind = "pin"
+	ConfigurationObject ObjectKind = "configuration"
+	BindingObject       ObjectKind = "binding"
+)
+
+// single difference, Old and New hold human-readable representation
+// of changed object: component type, pin declaration, configuration value etc;
+// pins of leaf components also have data types in OldType and NewType
+type Change struct {
+	Kind      ChangeKind `json:"kind"`
+	Object    ObjectKind `json:"object"`
+	Component string     `json:"component"`
+	Interface string     `json:"interface,omitempty"`
+	Pin       string     `json:"pin,omitempty"`
+	Key       string     `json:"key,omitempty"`
+	Old       string     `json:"old,omitempty"`
+	New       string     `json:"new,omitempty"`
+	OldType   string     `json:"oldType,omitempty"`
+	NewType   string     `json:"newType,omitempty"`
+}
+
+// location of changed object, e.g. x#myinterface.mypin
+func (c Change) Path() string {
+	path := c.Component
+	if c.Interface != "" {
+		path += "#" + c.Interface
+	}
+	if c.Pin != "" {
+		path += "." + c.Pin
+	}
+	if c.Key != "" {
+		path += "[" + c.Key + "]"
+	}
+	return path
+}
+
+func (c Change) String() string {
+	subject := string(c.Object)
+	if path := c.Path(); path != "" {
+		subject += " " + path
+	}
+	switch c.Kind {
+	case Added:
+		return fmt.Sprintf("+ %s: %s", subject, c.New)
+	case Removed:
+		return fmt.Sprintf("- %s: %s", subject, c.Old)
+	default:
+		return fmt.Sprintf("~ %s: %s -> %s", subject, c.Old, c.New)
+	}
+}
+
+// lists changes needed to turn old application into new one
+func Compare(old, new manifest.Application) []Change {
+	changes := []Change{}
+	compareComposite("", old.CompositeComponent, new.CompositeComponent, &changes)
+	return changes
+}
+
+func compareComposite(path string, old, new manifest.CompositeComponent, changes *[]Change) {
+	compareConfiguration(path, old.Configuration, new.Configuration, changes)
+	for _, name := range unionKeys(old.Interfaces, new.Interfaces) {
+		oldIface, inOld := old.Interfaces[name]
+		newIface, inNew := new.Interfaces[name]
+		switch {
+		case !inOld:
+			*changes = append(*changes, Change{Kind: Added, Object: InterfaceObject, Component: path, Interface: name, New: "composite"})
+		case !inNew:
+			*changes = append(*changes, Change{Kind: Removed, Object: InterfaceObject, Component: path, Interface: name, Old: "composite"})
+		default:
+			for _, pin := range unionKeys(oldIface, newIface) {
+				oldPin, inOld := oldIface[pin]
+				newPin, inNew := newIface[pin]
+				change := Change{Object: PinObject, Component: path, Interface: name, Pin: pin, Old: pinBindingName(oldPin), New: pinBindingName(newPin)}
+				switch {
+				case !inOld:
+					change.Kind, change.Old = Added, ""
+				case !inNew:
+					change.Kind, change.New = Removed, ""
+				case oldPin != newPin:
+					change.Kind = Changed
+				default:
+					continue
+				}
+				*changes = append(*changes, change)
+			}
+		}
+	}
+	for _, name := range unionKeys(old.Components, new.Components) {
+		compareComponent(join(path, name), old.Components[name], new.Components[name], changes)
+	}
+	compareBindings(path, old.Bindings, new.Bindings, changes)
+}
+
+func compareComponent(path string, old, new manifest.Component, changes *[]Change) {
+	switch {
+	case old == nil:
+		*changes = append(*changes, Change{Kind: Added, Object: ComponentObject, Component: path, New: componentName(new)})
+		return
+	case new == nil:
+		*changes = append(*changes, Change{Kind: Removed, Object: ComponentObject, Component: path, Old: componentName(old)})
+		return
+	}
+	oldLeaf, oldIsLeaf := old.(manifest.LeafComponent)
+	newLeaf, newIsLeaf := new.(manifest.LeafComponent)
+	oldComposite, oldIsComposite := old.(manifest.CompositeComponent)
+	newComposite, newIsComposite := new.(manifest.CompositeComponent)
+	switch {
+	case oldIsLeaf && newIsLeaf:
+		compareType(path, old, new, changes)
+		compareLeaf(path, oldLeaf, newLeaf, changes)
+	case oldIsComposite && newIsComposite:
+		compareType(path, old, new, changes)
+		compareComposite(path, oldComposite, newComposite, changes)
+	case reflect.TypeOf(old) == reflect.TypeOf(new):
+		// other kinds of components are compared by type and configuration only
+		compareType(path, old, new, changes)
+		compareConfiguration(path, old.GetConfiguration(), new.GetConfiguration(), changes)
+	default:
+		*changes = append(*changes,
+			Change{Kind: Removed, Object: ComponentObject, Component: path, Old: componentName(old)},
+			Change{Kind: Added, Object: ComponentObject, Component: path, New: componentName(new)})
+	}
+}
+
+func compareType(path string, old, new manifest.Component, changes *[]Change) {
+	if old.GetType() != new.GetType() {
+		*changes = append(*changes, Change{Kind: Changed, Object: ComponentObject, Component: path, Old: old.GetType().Name, New: new.GetType().Name})
+	}
+}
+
+func compareLeaf(path string, old, new manifest.LeafComponent, changes *[]Change) {
+	compareConfiguration(path, old.Configuration, new.Configuration, changes)
+	for _, name := range unionKeys(old.Interfaces, new.Interfaces) {
+		oldIface, inOld := old.Interfaces[name]
+		newIface, inNew := new.Interfaces[name]
+		switch {
+		case !inOld:
+			*changes = append(*changes, Change{Kind: Added, Object: InterfaceObject, Component: path, Interface: name, New: requiredName(newIface)})
+			continue
+		case !inNew:
+			*changes = append(*changes, Change{Kind: Removed, Object: InterfaceObject, Component: path, Interface: name, Old: requiredName(oldIface)})
+			continue
+		case oldIface.Required != newIface.Required:
+			*changes = append(*changes, Change{Kind: Changed, Object: InterfaceObject, Component: path, Interface: name, Old: requiredName(oldIface), New: requiredName(newIface)})
+		}
+		for _, pin := range unionKeys(oldIface.Pins, newIface.Pins) {
+			oldPin, inOld := oldIface.Pins[pin]
+			newPin, inNew := newIface.Pins[pin]
+			change := Change{Object: PinObject, Component: path, Interface: name, Pin: pin}
+			switch {
+			case !inOld:
+				change.Kind, change.New, change.NewType = Added, newPin.String(), pinDataType(newPin)
+			case !inNew:
+				change.Kind, change.Old, change.OldType = Removed, oldPin.String(), pinDataType(oldPin)
+			case !reflect.DeepEqual(oldPin, newPin):
+				change.Kind, change.Old, change.New = Changed, oldPin.String(), newPin.String()
+				change.OldType, change.NewType = pinDataType(oldPin), pinDataType(newPin)
+			default:
+				continue
+			}
+			*changes = append(*changes, change)
+		}
+	}
+}
+
+// data type of signal or configuration pin, "any" for untyped ones;
+// commands have arguments, progress and result as in declaration
+func pinDataType(pin manifest.DirectedPinType) string {
+	switch t := pin.PinType.(type) {
+	case manifest.SignalPin:
+		return dataTypeName(t.DataType)
+	case manifest.ConfigurationPin:
+		return dataTypeName(t.DataType)
+	case manifest.CommandPin:
+		name := t.Arguments.DataTypeName()
+		if len(t.Progress.Fields) != 0 {
+			name += " => " + t.Progress.DataTypeName()
+		}
+		if len(t.Progress.Fields) != 0 || len(t.Result.Fields) != 0 {
+			name += " => " + t.Result.DataTypeName()
+		}
+		return name
+	}
+	return ""
+}
+
+func dataTypeName(t datatype.DataType) string {
+	if t == nil {
+		return "any"
+	}
+	return t.DataTypeName()
+}
+
+func compareConfiguration(path string, old, new manifest.Configuration, changes *[]Change) {
+	for _, key := range unionKeys(old, new) {
+		oldValue, inOld := old[key]
+		newValue, inNew := new[key]
+		change := Change{Object: ConfigurationObject, Component: path, Key: key}
+		switch {
+		case !inOld:
+			change.Kind, change.New = Added, fmt.Sprint(newValue)
+		case !inNew:
+			change.Kind, change.Old = Removed, fmt.Sprint(oldValue)
+		case !reflect.DeepEqual(oldValue, newValue):
+			change.Kind, change.Old, change.New = Changed, fmt.Sprint(oldValue), fmt.Sprint(newValue)
+		default:
+			continue
+		}
+		*changes = append(*changes, change)
+	}
+}
+
+// bindings are compared as unordered sets of unordered pairs
+func compareBindings(path string, old, new []manifest.Binding, changes *[]Change) {
+	oldSet := bindingSet(old)
+	newSet := bindingSet(new)
+	for _, binding := range unionKeys(oldSet, newSet) {
+		switch {
+		case !oldSet[binding]:
+			*changes = append(*changes, Change{Kind: Added, Object: BindingObject, Component: path, New: binding})
+		case !newSet[binding]:
+			*changes = append(*changes, Change{Kind: Removed, Object: BindingObject, Component: path, Old: binding})
+		}
+	}
+}
+
+func bindingSet(bindings []manifest.Binding) map[string]bool {
+	set := make(map[string]bool)
+	for _, binding := range bindings {
+		sides := []string{manifest.FormatBindingTarget(binding.Left), manifest.FormatBindingTarget(binding.Right)}
+		sort.Strings(sides)
+		set["["+sides[0]+", "+sides[1]+"]"] = true
+	}
+	return set
+}
+
+func pinBindingName(binding manifest.PinBinding) string {
+	return binding.TargetComponent + "#" + binding.TargetPin.Interface + "." + binding.TargetPin.Pin
+}
+
+func componentName(c manifest.Component) string {
+	if _, ok := c.(manifest.CompositeComponent); ok {
+		return "composite " + c.GetType().Name
+	}
+	return c.GetType().Name
+}
+
+func requiredName(iface manifest.LeafInterface) string {
+	if iface.Required {
+		return "required"
+	}
+	return "optional"
+}
+
+func join(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// sorted keys present in any of maps
+func unionKeys(maps ...interface{}) []string {
+	set := make(map[string]bool)
+	for _, m := range maps {
+		v := reflect.ValueOf(m)
+		for _, key := range v.MapKeys() {
+			set[key.String()] = true
+		}
+	}
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
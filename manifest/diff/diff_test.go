@@ -0,0 +1,175 @@
+package diff
+
+import (
+	"github.com/chemikadze/gonomi/manifest"
+	"reflect"
+	"testing"
+)
+
+func parse(t *testing.T, m string) manifest.Application {
+	app, err := manifest.Parse(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return app
+}
+
+func TestCompareEqual(t *testing.T) {
+	app := parse(t, `
+        application:
+            components:
+                x:
+                    type: test.Component
+                    interfaces:
+                        i:
+                            p: publish-signal(string)
+    `)
+	if changes := Compare(app, app); len(changes) != 0 {
+		t.Error("No changes expected, got", changes)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	old := parse(t, `
+        application:
+            components:
+                x:
+                    type: test.Component
+                    configuration:
+                        a: 1
+                        b: 2
+                    interfaces:
+                        i:
+                            p: publish-signal(string)
+                            q: consume-signal(int)
+                        j:
+                            p: publish-signal(string)
+                y:
+                    type: test.Component
+                z:
+                    type: test.Old
+            bindings:
+                - [x, y]
+    `)
+	new := parse(t, `
+        application:
+            components:
+                x:
+                    type: test.Component
+                    configuration:
+                        a: 1
+                        b: 3
+                        c: 4
+                    interfaces:
+                        i:
+                            p: publish-signal(int)
+                            r: consume-signal(int)
+                        j:
+                            p: publish-signal(string)
+                    required: [j]
+                y:
+                    type: test.Component
+                z:
+                    type: test.New
+                w:
+                    type: test.Component
+            bindings:
+                - [y, x]
+                - [x#j, w]
+    `)
+	expected := []string{
+		"+ component w: test.Component",
+		"~ configuration x[b]: 2 -> 3",
+		"+ configuration x[c]: 4",
+		"~ pin x#i.p: publish-signal(string) -> publish-signal(int)",
+		"- pin x#i.q: consume-signal(int)",
+		"+ pin x#i.r: consume-signal(int)",
+		"~ interface x#j: optional -> required",
+		"~ component z: test.Old -> test.New",
+		"+ binding: [w, x#j]",
+	}
+	changes := Compare(old, new)
+	actual := make([]string, len(changes))
+	for i, change := range changes {
+		actual[i] = change.String()
+	}
+	if len(actual) != len(expected) {
+		t.Fatalf("\nChanges: %q\nExpect:  %q", actual, expected)
+	}
+	for i := range expected {
+		if actual[i] != expected[i] {
+			t.Errorf("\nChange: %s\nExpect: %s", actual[i], expected[i])
+		}
+	}
+}
+
+// component of kind diff knows nothing about
+type opaque struct {
+	manifest.Type
+	manifest.Configuration
+}
+
+func (o opaque) GetType() manifest.Type {
+	return o.Type
+}
+
+func (o opaque) GetConfiguration() map[string]interface{} {
+	return o.Configuration
+}
+
+func TestCompareOtherKinds(t *testing.T) {
+	old := manifest.Application{manifest.CompositeComponent{Components: map[string]manifest.Component{
+		"x": opaque{manifest.Type{"test.Old"}, manifest.Configuration{"a": 1}},
+	}}}
+	new := manifest.Application{manifest.CompositeComponent{Components: map[string]manifest.Component{
+		"x": opaque{manifest.Type{"test.New"}, manifest.Configuration{"a": 2}},
+	}}}
+	expected := []string{
+		"~ component x: test.Old -> test.New",
+		"~ configuration x[a]: 1 -> 2",
+	}
+	changes := Compare(old, new)
+	if len(changes) != len(expected) {
+		t.Fatalf("\nChanges: %v\nExpect:  %q", changes, expected)
+	}
+	for i := range expected {
+		if changes[i].String() != expected[i] {
+			t.Errorf("\nChange: %s\nExpect: %s", changes[i], expected[i])
+		}
+	}
+}
+
+func TestComparePinTypes(t *testing.T) {
+	old := parse(t, `
+        application:
+            components:
+                x:
+                    type: test.Component
+                    interfaces:
+                        i:
+                            p: publish-signal(string)
+                            q: publish-signal(int)
+                            c: receive-command(int x)
+    `)
+	new := parse(t, `
+        application:
+            components:
+                x:
+                    type: test.Component
+                    interfaces:
+                        i:
+                            p: publish-signal(list<string>)
+                            q: consume-signal(int)
+                            c: receive-command(int x => string y)
+                            u: consume-signal()
+    `)
+	expected := []Change{
+		{Changed, PinObject, "x", "i", "c", "", "receive-command(int x)", "receive-command(int x => string y)", "record<int x>", "record<int x> => record<string y>"},
+		{Changed, PinObject, "x", "i", "p", "", "publish-signal(string)", "publish-signal(list<string>)", "string", "list<string>"},
+		{Changed, PinObject, "x", "i", "q", "", "publish-signal(int)", "consume-signal(int)", "int", "int"},
+		{Added, PinObject, "x", "i", "u", "", "", "consume-signal()", "", "any"},
+	}
+	if changes := Compare(old, new); !reflect.DeepEqual(changes, expected) {
+		t.Errorf("\nChanges: %+v\nExpect:  %+v", changes, expected)
+	}
+}
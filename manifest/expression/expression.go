@@ -0,0 +1,311 @@
+// Package expression parses and evaluates {$.name} interpolations used in
+// workflow steps and configuration values, e.g. "http://{$.hosts[0]}:{$.port}".
+package expression
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// parsed string: literal text interleaved with references
+type Template struct {
+	Parts []Part
+}
+
+type Part interface {
+	// byte offset of part in source string
+	Position() int
+}
+
+type Text struct {
+	Value  string
+	Offset int
+}
+
+func (t Text) Position() int {
+	return t.Offset
+}
+
+// $.name.field[0] reference, Offset points to opening brace
+type Reference struct {
+	Path   []Segment
+	Offset int
+}
+
+func (r Reference) Position() int {
+	return r.Offset
+}
+
+// first path element, the name reference is resolved by
+func (r Reference) Root() string {
+	return r.Path[0].Name
+}
+
+func (r Reference) String() string {
+	s := "$"
+	for _, segment := range r.Path {
+		s += segment.String()
+	}
+	return s
+}
+
+// field access by name or list access by index
+type Segment struct {
+	Name    string
+	Index   int
+	IsIndex bool
+}
+
+func (s Segment) String() string {
+	if s.IsIndex {
+		return fmt.Sprintf("[%d]", s.Index)
+	}
+	if isIdentifier(s.Name) {
+		return "." + s.Name
+	}
+	return "[" + strconv.Quote(s.Name) + "]"
+}
+
+// error in expression syntax, Offset is position in source string
+type SyntaxError struct {
+	Offset  int
+	Message string
+}
+
+func (e SyntaxError) Error() string {
+	return fmt.Sprintf("column %d: %s", e.Offset+1, e.Message)
+}
+
+// parses string with interpolations, text outside of {$...} is kept as is
+func Parse(s string) (Template, error) {
+	t := Template{}
+	offset := 0
+	for offset < len(s) {
+		start := strings.Index(s[offset:], "{$")
+		if start < 0 {
+			t.Parts = append(t.Parts, Text{s[offset:], offset})
+			break
+		}
+		start += offset
+		if start > offset {
+			t.Parts = append(t.Parts, Text{s[offset:start], offset})
+		}
+		reference, end, err := parseReference(s, start)
+		if err != nil {
+			return Template{}, err
+		}
+		t.Parts = append(t.Parts, reference)
+		offset = end
+	}
+	return t, nil
+}
+
+// tells whether string has interpolations at all
+func Contains(s string) bool {
+	return strings.Contains(s, "{$")
+}
+
+// reference starting at {$, returns offset after closing brace
+func parseReference(s string, start int) (Reference, int, error) {
+	r := Reference{Offset: start}
+	i := start + 2
+	for {
+		if i >= len(s) {
+			return Reference{}, 0, SyntaxError{start, "unterminated expression"}
+		}
+		switch c := s[i]; {
+		case c == '}':
+			if len(r.Path) == 0 {
+				return Reference{}, 0, SyntaxError{i, "empty reference"}
+			}
+			return r, i + 1, nil
+		case c == '.':
+			end := i + 1
+			for end < len(s) && isIdentifierChar(s[end]) {
+				end++
+			}
+			if end == i+1 {
+				return Reference{}, 0, SyntaxError{i, "expected name after ."}
+			}
+			r.Path = append(r.Path, Segment{Name: s[i+1 : end]})
+			i = end
+		case c == '[':
+			end := strings.IndexByte(s[i:], ']')
+			if end < 0 {
+				return Reference{}, 0, SyntaxError{i, "unterminated ["}
+			}
+			end += i
+			body := strings.TrimSpace(s[i+1 : end])
+			if index, err := strconv.Atoi(body); err == nil {
+				r.Path = append(r.Path, Segment{Index: index, IsIndex: true})
+			} else if name, err := strconv.Unquote(body); err == nil && strings.HasPrefix(body, "\"") {
+				r.Path = append(r.Path, Segment{Name: name})
+			} else {
+				return Reference{}, 0, SyntaxError{i + 1, fmt.Sprintf("expected index or quoted name, got %s", body)}
+			}
+			if len(r.Path) == 1 && r.Path[0].IsIndex {
+				return Reference{}, 0, SyntaxError{i + 1, "reference has to start with name"}
+			}
+			i = end + 1
+		default:
+			return Reference{}, 0, SyntaxError{i, fmt.Sprintf("unexpected character %q", c)}
+		}
+	}
+}
+
+func (t Template) References() []Reference {
+	references := []Reference{}
+	for _, part := range t.Parts {
+		if r, ok := part.(Reference); ok {
+			references = append(references, r)
+		}
+	}
+	return references
+}
+
+func (t Template) String() string {
+	s := ""
+	for _, part := range t.Parts {
+		switch part := part.(type) {
+		case Text:
+			s += part.Value
+		case Reference:
+			s += "{" + part.String() + "}"
+		}
+	}
+	return s
+}
+
+// substitutes references with values found by root name; template consisting
+// of single reference keeps type of the value, otherwise values are formatted
+// into string
+func (t Template) Evaluate(values map[string]interface{}) (interface{}, error) {
+	if len(t.Parts) == 1 {
+		if r, ok := t.Parts[0].(Reference); ok {
+			return r.Evaluate(values)
+		}
+	}
+	s := ""
+	for _, part := range t.Parts {
+		switch part := part.(type) {
+		case Text:
+			s += part.Value
+		case Reference:
+			value, err := part.Evaluate(values)
+			if err != nil {
+				return nil, err
+			}
+			s += fmt.Sprint(value)
+		}
+	}
+	return s, nil
+}
+
+func (r Reference) Evaluate(values map[string]interface{}) (interface{}, error) {
+	value, ok := values[r.Root()]
+	if !ok {
+		return nil, r.error("unresolved reference %s", r)
+	}
+	for i, segment := range r.Path[1:] {
+		v := reflect.ValueOf(value)
+		switch {
+		case segment.IsIndex && (v.Kind() == reflect.Slice || v.Kind() == reflect.Array):
+			if segment.Index < 0 || segment.Index >= v.Len() {
+				return nil, r.error("%s: index %d out of range", Reference{r.Path[:i+2], r.Offset}, segment.Index)
+			}
+			value = v.Index(segment.Index).Interface()
+		case !segment.IsIndex && v.Kind() == reflect.Map && (v.Type().Key().Kind() == reflect.String || v.Type().Key().Kind() == reflect.Interface):
+			field := mapField(v, segment.Name)
+			if !field.IsValid() {
+				return nil, r.error("%s: no field %s", Reference{r.Path[:i+2], r.Offset}, segment.Name)
+			}
+			value = field.Interface()
+		default:
+			return nil, r.error("%s: can't access %s of %v", Reference{r.Path[:i+1], r.Offset}, segment, value)
+		}
+	}
+	return value, nil
+}
+
+// field of map with string keys, or of map with interface{} keys
+// as decoded from YAML, where keys like 1 or true are matched by text
+func mapField(v reflect.Value, name string) reflect.Value {
+	if v.Type().Key().Kind() == reflect.String {
+		return v.MapIndex(reflect.ValueOf(name).Convert(v.Type().Key()))
+	}
+	for _, key := range v.MapKeys() {
+		if fmt.Sprint(key.Interface()) == name {
+			return v.MapIndex(key)
+		}
+	}
+	return reflect.Value{}
+}
+
+func (r Reference) error(format string, args ...interface{}) error {
+	return errors.New(fmt.Sprintf("column %d: ", r.Offset+1) + fmt.Sprintf(format, args...))
+}
+
+// evaluates every string inside of value decoded from manifest
+func EvaluateValue(value interface{}, values map[string]interface{}) (interface{}, error) {
+	switch value := value.(type) {
+	case string:
+		if !Contains(value) {
+			return value, nil
+		}
+		t, err := Parse(value)
+		if err != nil {
+			return nil, err
+		}
+		return t.Evaluate(values)
+	case []interface{}:
+		result := make([]interface{}, len(value))
+		for i, item := range value {
+			evaluated, err := EvaluateValue(item, values)
+			if err != nil {
+				return nil, errors.New(fmt.Sprintf("[%d]: %s", i, err))
+			}
+			result[i] = evaluated
+		}
+		return result, nil
+	case map[interface{}]interface{}:
+		result := make(map[interface{}]interface{}, len(value))
+		for key, item := range value {
+			evaluated, err := EvaluateValue(item, values)
+			if err != nil {
+				return nil, errors.New(fmt.Sprintf("%v: %s", key, err))
+			}
+			result[key] = evaluated
+		}
+		return result, nil
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(value))
+		for key, item := range value {
+			evaluated, err := EvaluateValue(item, values)
+			if err != nil {
+				return nil, errors.New(fmt.Sprintf("%s: %s", key, err))
+			}
+			result[key] = evaluated
+		}
+		return result, nil
+	}
+	return value, nil
+}
+
+func isIdentifier(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if !isIdentifierChar(s[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func isIdentifierChar(c byte) bool {
+	return c == '_' || c == '-' || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9')
+}
@@ -0,0 +1,108 @@
+package expression
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	template, err := Parse(`http://{$.hosts[0]}:{$.config["http port"]}/`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := Template{[]Part{
+		Text{"http://", 0},
+		Reference{[]Segment{{Name: "hosts"}, {Index: 0, IsIndex: true}}, 7},
+		Text{":", 19},
+		Reference{[]Segment{{Name: "config"}, {Name: "http port"}}, 20},
+		Text{"/", 43},
+	}}
+	if !reflect.DeepEqual(template, expected) {
+		t.Errorf("\nParsed: %#v\nExpect: %#v", template, expected)
+	}
+	if template.String() != `http://{$.hosts[0]}:{$.config["http port"]}/` {
+		t.Error("Unexpected string", template.String())
+	}
+	if len(template.References()) != 2 || template.References()[1].Root() != "config" {
+		t.Error("Unexpected references", template.References())
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	cases := map[string]string{
+		"a {$.x":     "column 3: unterminated expression",
+		"{$}":        "column 3: empty reference",
+		"{$.}":       "column 3: expected name after .",
+		"{$.x[y]}":   "column 6: expected index or quoted name, got y",
+		"{$.x[0}":    "column 5: unterminated [",
+		"{$[0]}":     "column 4: reference has to start with name",
+		"{$.x + 1}":  "column 5: unexpected character ' '",
+		"{plain} $.": "",
+	}
+	for src, expected := range cases {
+		_, err := Parse(src)
+		if expected == "" {
+			if err != nil {
+				t.Error(src, err)
+			}
+			continue
+		}
+		if err == nil || err.Error() != expected {
+			t.Errorf("%s:\nRaised: %v\nExpect: %v", src, err, expected)
+		}
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	values := map[string]interface{}{
+		"hosts":  []interface{}{"10.0.0.1", "10.0.0.2"},
+		"config": map[interface{}]interface{}{"port": 8080, 1: "one"},
+		"codes":  map[int]string{200: "OK"},
+	}
+	cases := map[string]interface{}{
+		"{$.hosts}":                           []interface{}{"10.0.0.1", "10.0.0.2"},
+		"{$.config.port}":                     8080,
+		"{$.config.1}":                        "one",
+		"http://{$.hosts[1]}:{$.config.port}": "http://10.0.0.2:8080",
+	}
+	for src, expected := range cases {
+		template, err := Parse(src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		value, err := template.Evaluate(values)
+		if err != nil || !reflect.DeepEqual(value, expected) {
+			t.Errorf("%s: got %v (%v), expected %v", src, value, err, expected)
+		}
+	}
+	errors := map[string]string{
+		"{$.missing}":      "column 1: unresolved reference $.missing",
+		"x{$.hosts[2]}":    "column 2: $.hosts[2]: index 2 out of range",
+		"{$.config.host}":  "column 1: $.config.host: no field host",
+		"{$.hosts.length}": "column 1: $.hosts: can't access .length of [10.0.0.1 10.0.0.2]",
+		"{$.codes.ok}":     "column 1: $.codes: can't access .ok of map[200:OK]",
+	}
+	for src, expected := range errors {
+		template, _ := Parse(src)
+		if _, err := template.Evaluate(values); err == nil || err.Error() != expected {
+			t.Errorf("%s:\nRaised: %v\nExpect: %v", src, err, expected)
+		}
+	}
+}
+
+func TestEvaluateValue(t *testing.T) {
+	value, err := EvaluateValue(map[interface{}]interface{}{
+		"roles": []interface{}{"{$.role}", "static"},
+		"count": 1,
+	}, map[string]interface{}{"role": "web"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[interface{}]interface{}{"roles": []interface{}{"web", "static"}, "count": 1}
+	if !reflect.DeepEqual(value, expected) {
+		t.Errorf("\nEvaluated: %v\nExpect:    %v", value, expected)
+	}
+	if _, err := EvaluateValue([]interface{}{"{$.x}"}, nil); err == nil || err.Error() != "[0]: column 1: unresolved reference $.x" {
+		t.Error("Unexpected error:", err)
+	}
+}
@@ -0,0 +1,279 @@
+package manifest
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/chemikadze/gonomi/manifest/parsing"
+	yamlv2 "gopkg.in/yaml.v2"
+	"gopkg.in/yaml.v3"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// part of the stream with position of its first character in file
+type document struct {
+	src    string
+	line   int
+	column int
+}
+
+var yamlErrorLine = regexp.MustCompile(`^(?:yaml: )?line (\d+): (.*)$`)
+
+// parses every application of the stream: documents of YAML or values of
+// JSON when filename has .json extension; errors are parsing.PositionError
+// carrying filename and position in it when it is known
+func ParseReader(r io.Reader, filename string) ([]Application, error) {
+	src, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	docs, err := parseStream(src, filename)
+	if err != nil {
+		return nil, err
+	}
+	apps := make([]Application, 0, len(docs))
+	for _, doc := range docs {
+		apps = append(apps, doc.app)
+	}
+	return apps, nil
+}
+
+func ParseFile(path string) ([]Application, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ParseReader(f, path)
+}
+
+// application of the stream with sections parser leaves out
+type parsedDocument struct {
+	app     Application
+	imports []string
+}
+
+type importsSection struct {
+	Imports []string `yaml:"imports" json:"imports"`
+}
+
+func parseStream(src []byte, filename string) ([]parsedDocument, error) {
+	if strings.EqualFold(filepath.Ext(filename), ".json") {
+		return parseJSONStream(src, filename)
+	}
+	return parseYAMLStream(string(src), filename)
+}
+
+func parseYAMLStream(src string, filename string) ([]parsedDocument, error) {
+	nodes := []*yaml.Node{}
+	decoder := yaml.NewDecoder(strings.NewReader(src))
+	for {
+		node := &yaml.Node{}
+		err := decoder.Decode(node)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			// lines of decoder errors are counted from the start of the stream
+			return nil, document{"", 1, 1}.error(filename, err)
+		}
+		nodes = append(nodes, node)
+	}
+	lines := strings.SplitAfter(src, "\n")
+	docs := []parsedDocument{}
+	for i, node := range nodes {
+		if isEmptyDocument(node) {
+			continue
+		}
+		// document spans lines up to the start of the next one,
+		// leading comments of the stream stay in the first document
+		start, end := node.Line, len(lines)
+		if i == 0 {
+			start = 1
+		}
+		if i+1 < len(nodes) {
+			end = nodes[i+1].Line - 1
+		}
+		doc := document{strings.Join(lines[start-1:end], ""), start, 1}
+		// node positions are counted from the start of the stream too
+		if err := checkElements(node.Content[0]); err != nil {
+			return nil, document{"", 1, 1}.error(filename, err)
+		}
+		app, err := Parse(doc.src)
+		if err != nil {
+			return nil, doc.error(filename, err)
+		}
+		var section importsSection
+		if err := yamlv2.Unmarshal([]byte(doc.src), &section); err != nil {
+			return nil, doc.error(filename, err)
+		}
+		docs = append(docs, parsedDocument{app, section.Imports})
+	}
+	return docs, nil
+}
+
+func parseJSONStream(src []byte, filename string) ([]parsedDocument, error) {
+	decoder := json.NewDecoder(bytes.NewReader(src))
+	docs := []parsedDocument{}
+	for {
+		var raw json.RawMessage
+		err := decoder.Decode(&raw)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			if err, ok := err.(*json.SyntaxError); ok {
+				// offset points after the offending character
+				line, column := position(src, int(err.Offset)-1)
+				return nil, parsing.PositionError{filename, line, column, err.Error()}
+			}
+			return nil, parsing.PositionError{filename, 0, 0, err.Error()}
+		}
+		line, column := position(src, int(decoder.InputOffset())-len(raw))
+		doc := document{string(raw), line, column}
+		// JSON value is a valid YAML flow mapping, its nodes give
+		// positions of elements parser rejects
+		var node yaml.Node
+		if err := yaml.Unmarshal(raw, &node); err == nil && len(node.Content) != 0 {
+			if err := checkElements(node.Content[0]); err != nil {
+				return nil, doc.error(filename, err)
+			}
+		}
+		app, err := ParseJSON(doc.src)
+		if err != nil {
+			return nil, doc.error(filename, err)
+		}
+		var section importsSection
+		if err := json.Unmarshal(raw, &section); err != nil {
+			return nil, parsing.PositionError{filename, line, column, err.Error()}
+		}
+		docs = append(docs, parsedDocument{app, section.Imports})
+	}
+	return docs, nil
+}
+
+// document without content or with explicit start only
+func isEmptyDocument(node *yaml.Node) bool {
+	if len(node.Content) == 0 {
+		return true
+	}
+	content := node.Content[0]
+	return content.Kind == yaml.ScalarNode && content.Tag == "!!null" && content.Value == ""
+}
+
+// converts parser error to position in file, positions of errors
+// are relative to the document
+func (d document) error(filename string, err error) error {
+	line, column, message := 0, 0, err.Error()
+	switch e := err.(type) {
+	case parsing.ManifestError:
+		line, column, message = e.Line, e.Column, e.Message
+	case *yamlv2.TypeError:
+		if len(e.Errors) != 0 {
+			message = e.Errors[0]
+		}
+	}
+	if line == 0 {
+		if match := yamlErrorLine.FindStringSubmatch(message); match != nil {
+			line, _ = strconv.Atoi(match[1])
+			message = match[2]
+		}
+	}
+	if line != 0 {
+		if line == 1 && column != 0 {
+			column += d.column - 1
+		}
+		line += d.line - 1
+	}
+	return parsing.PositionError{filename, line, column, message}
+}
+
+// checks re-exports, pin declarations and bindings one by one,
+// so that error carries position of the element parser rejects
+func checkElements(root *yaml.Node) error {
+	app := mappingValue(root, "application")
+	for _, iface := range mappingValues(mappingValue(app, "interfaces")) {
+		for _, pin := range mappingValues(iface) {
+			if pin.Kind != yaml.ScalarNode {
+				continue
+			}
+			if _, err := ParsePinBinding(pin.Value); err != nil {
+				return nodeError(pin, err)
+			}
+		}
+	}
+	for _, component := range mappingValues(mappingValue(app, "components")) {
+		for _, iface := range mappingValues(mappingValue(component, "interfaces")) {
+			for _, pin := range mappingValues(iface) {
+				if pin.Kind != yaml.ScalarNode {
+					continue
+				}
+				if _, err := parseDirectedPinType(pin.Value); err != nil {
+					return nodeError(pin, err)
+				}
+			}
+		}
+	}
+	if bindings := mappingValue(app, "bindings"); bindings != nil && bindings.Kind == yaml.SequenceNode {
+		for _, binding := range bindings.Content {
+			binding = resolveAlias(binding)
+			targets := []string{}
+			for _, target := range binding.Content {
+				targets = append(targets, resolveAlias(target).Value)
+			}
+			if _, err := parseBindings([][]string{targets}); err != nil {
+				return nodeError(binding, err)
+			}
+		}
+	}
+	return nil
+}
+
+// error positioned at node
+func nodeError(node *yaml.Node, err error) error {
+	if e, ok := err.(parsing.ManifestError); ok {
+		return parsing.ManifestError{e.Message, node.Line, node.Column}
+	}
+	return parsing.ManifestError{err.Error(), node.Line, node.Column}
+}
+
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	node = resolveAlias(node)
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if resolveAlias(node.Content[i]).Value == key {
+			return resolveAlias(node.Content[i+1])
+		}
+	}
+	return nil
+}
+
+func mappingValues(node *yaml.Node) []*yaml.Node {
+	node = resolveAlias(node)
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	values := make([]*yaml.Node, 0, len(node.Content)/2)
+	for i := 1; i < len(node.Content); i += 2 {
+		values = append(values, resolveAlias(node.Content[i]))
+	}
+	return values
+}
+
+// 1-based line and column of byte offset
+func position(src []byte, offset int) (int, int) {
+	if offset > len(src) {
+		offset = len(src)
+	}
+	before := src[:offset]
+	line := bytes.Count(before, []byte("\n")) + 1
+	column := offset - bytes.LastIndexByte(before, '\n')
+	return line, column
+}
@@ -0,0 +1,150 @@
+package manifest
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const multiDocument = `# first
+application:
+    components:
+        x:
+            type: test.Component
+---
+application:
+    components:
+        y:
+            type: test.Component
+...
+---
+# empty document
+`
+
+func TestParseReaderMultiDocument(t *testing.T) {
+	apps, err := ParseReader(strings.NewReader(multiDocument), "apps.yml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(apps) != 2 {
+		t.Fatal("Two applications expected, got", apps)
+	}
+	if _, ok := apps[0].Components["x"]; !ok {
+		t.Error("First application should have x, got", apps[0])
+	}
+	if _, ok := apps[1].Components["y"]; !ok {
+		t.Error("Second application should have y, got", apps[1])
+	}
+}
+
+func TestParseReaderErrorPosition(t *testing.T) {
+	cases := map[string]string{
+		multiDocument + `---
+application:
+    components:
+        z:
+            type: test.Component
+            interfaces:
+                i:
+                    p: plubish-signal(string)
+`: "apps.yml:21:24: Unknown pin type: plubish-signal",
+		multiDocument + `---
+application:
+    bindings:
+        - [x, "y#"]
+`: "apps.yml:17:11: Unexpected binding target: y#",
+		multiDocument + `---
+application:
+    components: [x]
+`: "apps.yml:16: cannot unmarshal !!seq into map[string]manifest.component",
+		"application:\n  - x\n x: y\n": "apps.yml:2: did not find expected key",
+	}
+	for src, expected := range cases {
+		_, err := ParseReader(strings.NewReader(src), "apps.yml")
+		if err == nil || err.Error() != expected {
+			t.Errorf("\nRaised: %v\nExpect: %v", err, expected)
+		}
+	}
+}
+
+func TestParseReaderDocumentMarkers(t *testing.T) {
+	src := `application:
+    components:
+        x:
+            type: test.Component
+            configuration:
+                separator: "
+                    ---
+                    "
+                list: [a,
+                    --- ,b]
+... # end of first document
+--- # second document
+application:
+    components:
+        y:
+            type: test.Component
+`
+	apps, err := ParseReader(strings.NewReader(src), "apps.yml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(apps) != 2 {
+		t.Fatal("Two applications expected, got", apps)
+	}
+	if _, ok := apps[1].Components["y"]; !ok {
+		t.Error("Second application should have y, got", apps[1])
+	}
+	_, err = ParseReader(strings.NewReader(src+`            interfaces:
+                i:
+                    p: plubish-signal(string)
+                    q: publish-signal(string)
+                j:
+                    p: plubish-signal(string)
+`), "apps.yml")
+	if err == nil || err.Error() != "apps.yml:19:24: Unknown pin type: plubish-signal" {
+		t.Error("Error at the first broken pin expected, got", err)
+	}
+}
+
+func TestParseReaderJSON(t *testing.T) {
+	apps, err := ParseReader(strings.NewReader(`{"application": {"components": {"x": {"type": "test.Component"}}}}
+{"application": {}}`), "apps.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(apps) != 2 {
+		t.Fatal("Two applications expected, got", apps)
+	}
+	cases := map[string]string{
+		"{}\n{\"application\": {\n    \"bindings\": [[\"x\", \"#\"]]}}": "apps.json:3:18: Unexpected binding target: #",
+		"{}\n{\"application\": }": "apps.json:2:17: invalid character '}' looking for beginning of value",
+	}
+	for src, expected := range cases {
+		_, err := ParseReader(strings.NewReader(src), "apps.json")
+		if err == nil || err.Error() != expected {
+			t.Errorf("\nRaised: %v\nExpect: %v", err, expected)
+		}
+	}
+}
+
+func TestParseFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gonomi")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "apps.yml")
+	if err := ioutil.WriteFile(path, []byte(multiDocument), 0644); err != nil {
+		t.Fatal(err)
+	}
+	apps, err := ParseFile(path)
+	if err != nil || len(apps) != 2 {
+		t.Error("Two applications expected, got", apps, err)
+	}
+	if _, err := ParseFile(filepath.Join(dir, "missing.yml")); err == nil {
+		t.Error("Error expected for missing file")
+	}
+}
@@ -0,0 +1,242 @@
+package manifest
+
+import (
+	"fmt"
+	"sort"
+)
+
+// pin of a particular leaf component
+type Endpoint struct {
+	Component ComponentId
+	Pin       PinId
+}
+
+func (e Endpoint) String() string {
+	return e.Component.String() + "#" + e.Pin.Interface + "." + e.Pin.Pin
+}
+
+// pin-to-pin connection, From is publish-signal or send-command side
+type Connection struct {
+	From Endpoint
+	To   Endpoint
+}
+
+type FlatComponent struct {
+	Id        ComponentId
+	Component LeafComponent
+}
+
+// effective wiring of application: all leaf components and connections
+// between their pins after following bindings and re-exports
+type Graph struct {
+	// in the order of Walk
+	Components []FlatComponent
+	// in the order of bindings
+	Connections []Connection
+}
+
+// pin re-exported by composite resolved to the leaf it belongs to
+type resolvedPin struct {
+	Endpoint Endpoint
+	PinType  DirectedPinType
+}
+
+// builds leaf-level graph; bindings are paired by interfaces: explicitly named
+// on both sides, named on one side with the same name on the other, or all
+// interfaces with the same names; pins are connected when they have the same
+// name, kind and opposite directions
+func Flatten(app Application) (Graph, error) {
+	g := Graph{}
+	var err error
+	Walk(app, VisitorFuncs{
+		OnEnterComponent: func(id ComponentId, c Component) WalkAction {
+			if leaf, ok := c.(LeafComponent); ok {
+				g.Components = append(g.Components, FlatComponent{id, leaf})
+			}
+			return Continue
+		},
+		OnCompositeInterface: func(id ComponentId, name string, iface CompositeInterface) WalkAction {
+			for pin := range iface {
+				if _, err = app.resolvePin(id, PinId{name, pin}, nil); err != nil {
+					return Stop
+				}
+			}
+			return Continue
+		},
+		OnBinding: func(id ComponentId, binding Binding) WalkAction {
+			var connections []Connection
+			connections, err = app.bindingConnections(id, binding)
+			g.Connections = append(g.Connections, connections...)
+			if err != nil {
+				return Stop
+			}
+			return Continue
+		},
+	})
+	if err != nil {
+		return Graph{}, err
+	}
+	return g, nil
+}
+
+// follows re-exports down to leaf pin, visited guards against composite
+// interfaces re-exporting each other
+func (a Application) resolvePin(id ComponentId, pin PinId, visited map[string]bool) (resolvedPin, error) {
+	c, err := a.Lookup(id)
+	if err != nil {
+		return resolvedPin{}, err
+	}
+	switch c := c.(type) {
+	case LeafComponent:
+		iface, ok := c.Interfaces[pin.Interface]
+		if !ok {
+			return resolvedPin{}, LookupError{id, -1, fmt.Sprintf("Component %s: no interface %s", id, pin.Interface)}
+		}
+		pinType, ok := iface.Pins[pin.Pin]
+		if !ok {
+			return resolvedPin{}, LookupError{id, -1, fmt.Sprintf("Component %s: no pin %s in interface %s", id, pin.Pin, pin.Interface)}
+		}
+		return resolvedPin{Endpoint{id, pin}, pinType}, nil
+	case CompositeComponent:
+		iface, ok := c.Interfaces[pin.Interface]
+		if !ok {
+			return resolvedPin{}, LookupError{id, -1, fmt.Sprintf("Component %s: no interface %s", id, pin.Interface)}
+		}
+		binding, ok := iface[pin.Pin]
+		if !ok {
+			return resolvedPin{}, LookupError{id, -1, fmt.Sprintf("Component %s: no pin %s in interface %s", id, pin.Pin, pin.Interface)}
+		}
+		if visited == nil {
+			visited = make(map[string]bool)
+		}
+		key := Endpoint{id, pin}.String()
+		if visited[key] {
+			return resolvedPin{}, LookupError{id, -1, fmt.Sprintf("Component %s: re-export cycle through %s.%s", id, pin.Interface, pin.Pin)}
+		}
+		visited[key] = true
+		// empty target refers to other interface of the composite itself
+		target := id
+		if binding.TargetComponent != "" {
+			child, err := ParseComponentId(binding.TargetComponent)
+			if err != nil {
+				return resolvedPin{}, LookupError{id, -1, fmt.Sprintf("Component %s: pin %s.%s: %s", id, pin.Interface, pin.Pin, err)}
+			}
+			target = ComponentId{append(append([]string{}, id.Path...), child.Path...)}
+		}
+		resolved, err := a.resolvePin(target, binding.TargetPin, visited)
+		if err != nil {
+			return resolvedPin{}, LookupError{id, -1, fmt.Sprintf("Component %s: re-export %s.%s: %s", id, pin.Interface, pin.Pin, err)}
+		}
+		return resolved, nil
+	}
+	return resolvedPin{}, LookupError{id, -1, fmt.Sprintf("Component %s: unsupported component %T", id, c)}
+}
+
+// interfaces of component as seen by its siblings
+func (a Application) exposedInterfaces(id ComponentId) (map[string]map[string]resolvedPin, error) {
+	c, err := a.Lookup(id)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]map[string]resolvedPin)
+	switch c := c.(type) {
+	case LeafComponent:
+		for name, iface := range c.Interfaces {
+			pins := make(map[string]resolvedPin)
+			for pin, pinType := range iface.Pins {
+				pins[pin] = resolvedPin{Endpoint{id, PinId{name, pin}}, pinType}
+			}
+			result[name] = pins
+		}
+	case CompositeComponent:
+		for name, iface := range c.Interfaces {
+			pins := make(map[string]resolvedPin)
+			for pin := range iface {
+				resolved, err := a.resolvePin(id, PinId{name, pin}, nil)
+				if err != nil {
+					return nil, err
+				}
+				pins[pin] = resolved
+			}
+			result[name] = pins
+		}
+	default:
+		return nil, LookupError{id, -1, fmt.Sprintf("Component %s: unsupported component %T", id, c)}
+	}
+	return result, nil
+}
+
+func (a Application) bindingConnections(composite ComponentId, binding Binding) ([]Connection, error) {
+	left, leftIface, err := a.bindingSide(composite, binding.Left)
+	if err != nil {
+		return nil, err
+	}
+	right, rightIface, err := a.bindingSide(composite, binding.Right)
+	if err != nil {
+		return nil, err
+	}
+	pairs := [][2]string{}
+	switch {
+	case leftIface != "" && rightIface != "":
+		pairs = append(pairs, [2]string{leftIface, rightIface})
+	case leftIface != "":
+		pairs = append(pairs, [2]string{leftIface, leftIface})
+	case rightIface != "":
+		pairs = append(pairs, [2]string{rightIface, rightIface})
+	default:
+		for _, name := range interfaceNames(left) {
+			pairs = append(pairs, [2]string{name, name})
+		}
+	}
+	connections := []Connection{}
+	for _, pair := range pairs {
+		leftPins, rightPins := left[pair[0]], right[pair[1]]
+		for _, name := range pinNames(leftPins) {
+			l := leftPins[name]
+			r, ok := rightPins[name]
+			if !ok || l.PinType.PinType.PinTypeName() != r.PinType.PinType.PinTypeName() {
+				continue
+			}
+			if l.PinType.Direction.IsSend() && r.PinType.Direction.IsReceive() {
+				connections = append(connections, Connection{l.Endpoint, r.Endpoint})
+			} else if l.PinType.Direction.IsReceive() && r.PinType.Direction.IsSend() {
+				connections = append(connections, Connection{r.Endpoint, l.Endpoint})
+			}
+		}
+	}
+	return connections, nil
+}
+
+func (a Application) bindingSide(composite ComponentId, target BindingTarget) (map[string]map[string]resolvedPin, string, error) {
+	iface := ""
+	if target, ok := target.(InterfaceBindingTarget); ok {
+		iface = target.Interface
+	}
+	id := ComponentId{append(append([]string{}, composite.Path...), BindingTargetComponent(target).Path...)}
+	interfaces, err := a.exposedInterfaces(id)
+	if err != nil {
+		return nil, "", err
+	}
+	if _, ok := interfaces[iface]; iface != "" && !ok {
+		return nil, "", LookupError{id, -1, fmt.Sprintf("Binding to unknown interface: %s#%s", id, iface)}
+	}
+	return interfaces, iface, nil
+}
+
+func interfaceNames(interfaces map[string]map[string]resolvedPin) []string {
+	names := make([]string, 0, len(interfaces))
+	for name := range interfaces {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func pinNames(pins map[string]resolvedPin) []string {
+	names := make([]string, 0, len(pins))
+	for name := range pins {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
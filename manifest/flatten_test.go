@@ -0,0 +1,101 @@
+package manifest
+
+import (
+	"github.com/chemikadze/gonomi/manifest/datatype"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func endpoint(path string, iface string, pin string) Endpoint {
+	return Endpoint{ComponentId{strings.Split(path, ".")}, PinId{iface, pin}}
+}
+
+func TestFlatten(t *testing.T) {
+	app := Application{CompositeComponent{
+		Components: map[string]Component{
+			"db": CompositeComponent{
+				Components: map[string]Component{
+					"server": LeafComponent{Interfaces: map[string]LeafInterface{
+						"sql": LeafInterface{Pins: map[string]DirectedPinType{
+							"url":   {Sends, SignalPin{datatype.String{}}},
+							"query": {Receives, CommandPin{}},
+						}},
+					}},
+				},
+				Interfaces: map[string]CompositeInterface{
+					"sql": CompositeInterface{
+						"url":   PinBinding{"server", PinId{"sql", "url"}},
+						"query": PinBinding{"server", PinId{"sql", "query"}},
+					},
+				},
+			},
+			"app": LeafComponent{Interfaces: map[string]LeafInterface{
+				"sql": LeafInterface{Pins: map[string]DirectedPinType{
+					"url":   {Receives, SignalPin{datatype.String{}}},
+					"query": {Sends, CommandPin{}},
+					"other": {Receives, SignalPin{datatype.String{}}},
+				}, Required: true},
+			}},
+		},
+		Bindings: []Binding{
+			{ComponentBindingTarget{ComponentId{[]string{"app"}}}, InterfaceBindingTarget{ComponentId{[]string{"db"}}, "sql"}},
+		},
+	}}
+	graph, err := Flatten(app)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(graph.Components) != 2 || graph.Components[0].Id.String() != "app" || graph.Components[1].Id.String() != "db.server" {
+		t.Error("Unexpected components", graph.Components)
+	}
+	expected := []Connection{
+		{endpoint("app", "sql", "query"), endpoint("db.server", "sql", "query")},
+		{endpoint("db.server", "sql", "url"), endpoint("app", "sql", "url")},
+	}
+	if !reflect.DeepEqual(graph.Connections, expected) {
+		t.Errorf("\nConnections: %v\nExpect:      %v", graph.Connections, expected)
+	}
+}
+
+func TestFlattenDanglingReexport(t *testing.T) {
+	_, err := Flatten(walkApp)
+	if err == nil {
+		t.Fatal("Error expected")
+	}
+	if !strings.Contains(err.Error(), "Component b: re-export e.p") {
+		t.Error("Unexpected error:", err)
+	}
+}
+
+func TestFlattenReexportCycle(t *testing.T) {
+	app := Application{CompositeComponent{Components: map[string]Component{
+		"c": CompositeComponent{Interfaces: map[string]CompositeInterface{
+			"a": CompositeInterface{"p": PinBinding{"", PinId{"b", "p"}}},
+			"b": CompositeInterface{"p": PinBinding{"", PinId{"a", "p"}}},
+		}},
+	}}}
+	_, err := Flatten(app)
+	if err == nil || !strings.Contains(err.Error(), "re-export cycle") {
+		t.Error("Cycle error expected, got", err)
+	}
+}
+
+func TestFlattenUnknownInterface(t *testing.T) {
+	app, err := Parse(`
+        application:
+            components:
+                x:
+                    type: test.Component
+                y:
+                    type: test.Component
+            bindings:
+                - [x#i, y]
+    `)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Flatten(app); err == nil {
+		t.Error("Error expected")
+	}
+}
@@ -0,0 +1,189 @@
+// Package format rewrites manifests in canonical form: pin types are
+// normalized, keys are sorted in stable order and comments are preserved.
+package format
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/chemikadze/gonomi/manifest"
+	"gopkg.in/yaml.v3"
+	"io"
+	"sort"
+)
+
+const indent = 4
+
+// well-known keys go first in this order, the rest are sorted by name
+var (
+	rootOrder        = []string{"imports", "application", "services", "policies"}
+	applicationOrder = []string{"configuration", "interfaces", "components", "bindings"}
+	componentOrder   = []string{"type", "configuration", "interfaces", "required", "components", "bindings"}
+)
+
+// formats every document of YAML stream
+func Format(src []byte) ([]byte, error) {
+	decoder := yaml.NewDecoder(bytes.NewReader(src))
+	var out bytes.Buffer
+	encoder := yaml.NewEncoder(&out)
+	encoder.SetIndent(indent)
+	for {
+		var doc yaml.Node
+		err := decoder.Decode(&doc)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if err := formatDocument(&doc); err != nil {
+			return nil, err
+		}
+		if err := encoder.Encode(&doc); err != nil {
+			return nil, err
+		}
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+func formatDocument(doc *yaml.Node) error {
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil
+	}
+	root := doc.Content[0]
+	sortKeys(root, rootOrder)
+	if app := lookup(root, "application"); app != nil {
+		return formatComposite(app, applicationOrder)
+	}
+	return nil
+}
+
+func formatComposite(node *yaml.Node, order []string) error {
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+	sortKeys(node, order)
+	if configuration := lookup(node, "configuration"); configuration != nil {
+		sortKeys(configuration, nil)
+	}
+	if interfaces := lookup(node, "interfaces"); interfaces != nil {
+		sortKeys(interfaces, nil)
+	}
+	if bindings := lookup(node, "bindings"); bindings != nil && bindings.Kind == yaml.SequenceNode {
+		bindings.Style = 0
+		for _, binding := range bindings.Content {
+			if binding.Kind == yaml.SequenceNode {
+				binding.Style = yaml.FlowStyle
+			}
+		}
+	}
+	components := lookup(node, "components")
+	if components == nil || components.Kind != yaml.MappingNode {
+		return nil
+	}
+	sortKeys(components, nil)
+	for i := 1; i < len(components.Content); i += 2 {
+		if err := formatComponent(components.Content[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func formatComponent(node *yaml.Node) error {
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+	if lookup(node, "components") != nil {
+		return formatComposite(node, componentOrder)
+	}
+	sortKeys(node, componentOrder)
+	if configuration := lookup(node, "configuration"); configuration != nil {
+		sortKeys(configuration, nil)
+	}
+	if required := lookup(node, "required"); required != nil && required.Kind == yaml.SequenceNode {
+		required.Style = yaml.FlowStyle
+		sort.SliceStable(required.Content, func(i, j int) bool {
+			return required.Content[i].Value < required.Content[j].Value
+		})
+	}
+	interfaces := lookup(node, "interfaces")
+	if interfaces == nil || interfaces.Kind != yaml.MappingNode {
+		return nil
+	}
+	sortKeys(interfaces, nil)
+	for i := 1; i < len(interfaces.Content); i += 2 {
+		pins := interfaces.Content[i]
+		if pins.Kind != yaml.MappingNode {
+			continue
+		}
+		sortKeys(pins, nil)
+		for j := 1; j < len(pins.Content); j += 2 {
+			if err := formatPin(pins.Content[j]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func formatPin(node *yaml.Node) error {
+	if node.Kind != yaml.ScalarNode {
+		return nil
+	}
+	pin, err := manifest.ParsePinType(node.Value)
+	if err != nil {
+		return errors.New(fmt.Sprintf("%d:%d: %s", node.Line, node.Column, err))
+	}
+	node.Value = pin.String()
+	node.Style = 0
+	return nil
+}
+
+func lookup(mapping *yaml.Node, key string) *yaml.Node {
+	if mapping.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i+1]
+		}
+	}
+	return nil
+}
+
+type pair struct {
+	key, value *yaml.Node
+}
+
+// reorders mapping keys: ones listed in order go first, the rest alphabetically
+func sortKeys(mapping *yaml.Node, order []string) {
+	if mapping.Kind != yaml.MappingNode {
+		return
+	}
+	rank := make(map[string]int)
+	for i, key := range order {
+		rank[key] = i - len(order)
+	}
+	pairs := make([]pair, 0, len(mapping.Content)/2)
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		pairs = append(pairs, pair{mapping.Content[i], mapping.Content[i+1]})
+	}
+	sort.SliceStable(pairs, func(i, j int) bool {
+		ri, rj := rank[pairs[i].key.Value], rank[pairs[j].key.Value]
+		if ri != rj {
+			return ri < rj
+		}
+		if ri < 0 {
+			return false
+		}
+		return pairs[i].key.Value < pairs[j].key.Value
+	})
+	mapping.Content = mapping.Content[:0]
+	for _, p := range pairs {
+		mapping.Content = append(mapping.Content, p.key, p.value)
+	}
+}
@@ -0,0 +1,104 @@
+package format
+
+import (
+	"testing"
+)
+
+const messy = `# leading comment
+application:
+  bindings:
+    - [x, y]
+    -
+      - y#i
+      - x
+  components:
+    y:
+      type: test.Component
+    x:
+      required:
+        - myrequired
+        - a
+      interfaces:
+        myinterface:
+          mypin2: "consume-signal( list< list <string> > )"  # kept
+          mypin1: publish-signal(map<string,int>)
+      type: test.Component
+`
+
+const canonical = `# leading comment
+application:
+    components:
+        x:
+            type: test.Component
+            interfaces:
+                myinterface:
+                    mypin1: publish-signal(map<string, int>)
+                    mypin2: consume-signal(list<list<string>>) # kept
+            required: [a, myrequired]
+        y:
+            type: test.Component
+    bindings:
+        - [x, y]
+        - [y#i, x]
+`
+
+func TestFormat(t *testing.T) {
+	formatted, err := Format([]byte(messy))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(formatted) != canonical {
+		t.Errorf("\nFormatted:\n%s\nExpect:\n%s", formatted, canonical)
+	}
+}
+
+func TestFormatIdempotent(t *testing.T) {
+	formatted, err := Format([]byte(canonical))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(formatted) != canonical {
+		t.Errorf("\nFormatted:\n%s\nExpect:\n%s", formatted, canonical)
+	}
+}
+
+func TestFormatMultipleDocuments(t *testing.T) {
+	formatted, err := Format([]byte("application: {}\n---\napplication: {}\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(formatted) != "application: {}\n---\napplication: {}\n" {
+		t.Errorf("Unexpected output:\n%s", formatted)
+	}
+}
+
+func TestFormatError(t *testing.T) {
+	_, err := Format([]byte(`
+application:
+    components:
+        x:
+            interfaces:
+                i:
+                    p: plubish-signal(string)
+`))
+	if err == nil || err.Error() != "7:24: Unknown pin type: plubish-signal" {
+		t.Error("Expected error with position, got", err)
+	}
+}
+
+func TestFormatMalformedDataType(t *testing.T) {
+	formatted, err := Format([]byte(`
+application:
+    components:
+        x:
+            interfaces:
+                i:
+                    p: publish-signal(lst<string>)
+`))
+	if err == nil || err.Error() != "7:24: Malformed data type in publish-signal(lst<string>): Unknown type lst" {
+		t.Error("Expected error with position, got", err)
+	}
+	if formatted != nil {
+		t.Errorf("Nothing expected to be formatted, got:\n%s", formatted)
+	}
+}
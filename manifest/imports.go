@@ -0,0 +1,135 @@
+package manifest
+
+import (
+	"fmt"
+	"github.com/chemikadze/gonomi/manifest/parsing"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// component name to path of the file it is defined in
+type Provenance map[string]string
+
+type LoadedApplication struct {
+	Application Application
+	Provenance  Provenance
+}
+
+// parses manifest file and adds components of files listed in its
+// imports section:
+//
+//	imports:
+//	    - lib/databases.yml
+//	    - lib/balancers/
+//
+// paths are relative to importing file, directory imports every manifest in it;
+// imports are resolved recursively, bindings of imported files are not used
+func LoadFile(path string) ([]LoadedApplication, error) {
+	return loadFile(path, nil)
+}
+
+func loadFile(path string, chain []string) ([]LoadedApplication, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	for i, importing := range chain {
+		if other, _ := filepath.Abs(importing); other == abs {
+			cycle := append(append([]string{}, chain[i:]...), path)
+			return nil, parsing.PositionError{chain[len(chain)-1], 0, 0, "Import cycle: " + strings.Join(cycle, " -> ")}
+		}
+	}
+	src, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	docs, err := parseStream(src, path)
+	if err != nil {
+		return nil, err
+	}
+	chain = append(append([]string{}, chain...), path)
+	loaded := make([]LoadedApplication, 0, len(docs))
+	for _, doc := range docs {
+		app := doc.app
+		provenance := Provenance{}
+		for name := range app.Components {
+			provenance[name] = path
+		}
+		for _, entry := range doc.imports {
+			if !filepath.IsAbs(entry) {
+				entry = filepath.Join(filepath.Dir(path), entry)
+			}
+			files, err := importedFiles(entry)
+			if err != nil {
+				return nil, parsing.PositionError{path, 0, 0, fmt.Sprintf("Import %s: %s", entry, err)}
+			}
+			for _, file := range files {
+				libraries, err := loadFile(file, chain)
+				if err != nil {
+					return nil, err
+				}
+				for _, library := range libraries {
+					if err := merge(&app, provenance, library); err != nil {
+						return nil, parsing.PositionError{path, 0, 0, err.Error()}
+					}
+				}
+			}
+		}
+		loaded = append(loaded, LoadedApplication{app, provenance})
+	}
+	return loaded, nil
+}
+
+// file itself or manifests of directory sorted by name
+func importedFiles(path string) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+	infos, err := ioutil.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+	files := []string{}
+	for _, info := range infos {
+		switch strings.ToLower(filepath.Ext(info.Name())) {
+		case ".yml", ".yaml", ".json":
+			if !info.IsDir() {
+				files = append(files, filepath.Join(path, info.Name()))
+			}
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// adds components of library, the same file may be imported several times
+func merge(app *Application, provenance Provenance, library LoadedApplication) error {
+	for name, c := range library.Application.Components {
+		source := library.Provenance[name]
+		if defined, ok := provenance[name]; ok {
+			if sameFile(defined, source) {
+				continue
+			}
+			return parsing.ManifestError{fmt.Sprintf("Component %s: defined in %s and %s", name, defined, source), 0, 0}
+		}
+		if app.Components == nil {
+			app.Components = make(map[string]Component)
+		}
+		app.Components[name] = c
+		provenance[name] = source
+	}
+	return nil
+}
+
+func sameFile(a, b string) bool {
+	absA, errA := filepath.Abs(a)
+	absB, errB := filepath.Abs(b)
+	return errA == nil && errB == nil && absA == absB
+}
@@ -0,0 +1,114 @@
+package manifest
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// writes files relative to new temporary directory
+func writeFiles(t *testing.T, files map[string]string) string {
+	dir, err := ioutil.TempDir("", "gonomi")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestLoadFile(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"app/main.yml": `
+imports:
+    - ../lib/
+    - common.yml
+application:
+    components:
+        web:
+            type: test.Web
+    bindings:
+        - [web, db]
+`,
+		"app/common.yml": `
+imports: [../lib/db.yml]
+application:
+    components:
+        cache:
+            type: test.Cache
+`,
+		"lib/db.yml": `
+application:
+    components:
+        db:
+            type: test.Database
+`,
+		"lib/lb.json": `{"application": {"components": {"lb": {"type": "test.Balancer"}}}}`,
+		"lib/README":  `not a manifest`,
+	})
+	defer os.RemoveAll(dir)
+	loaded, err := LoadFile(filepath.Join(dir, "app", "main.yml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded) != 1 {
+		t.Fatal("One application expected, got", loaded)
+	}
+	types := map[string]string{}
+	for name, c := range loaded[0].Application.Components {
+		types[name] = c.GetType().Name
+	}
+	expected := map[string]string{"web": "test.Web", "cache": "test.Cache", "db": "test.Database", "lb": "test.Balancer"}
+	if !reflect.DeepEqual(types, expected) {
+		t.Errorf("\nLoaded: %v\nExpect: %v", types, expected)
+	}
+	provenance := Provenance{
+		"web":   filepath.Join(dir, "app", "main.yml"),
+		"cache": filepath.Join(dir, "app", "common.yml"),
+		"db":    filepath.Join(dir, "lib", "db.yml"),
+		"lb":    filepath.Join(dir, "lib", "lb.json"),
+	}
+	if !reflect.DeepEqual(loaded[0].Provenance, provenance) {
+		t.Errorf("\nProvenance: %v\nExpect:     %v", loaded[0].Provenance, provenance)
+	}
+	if len(loaded[0].Application.Bindings) != 1 {
+		t.Error("Bindings of importing file should be kept", loaded[0].Application.Bindings)
+	}
+}
+
+func TestLoadFileErrors(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"cycle/a.yml":    "imports: [b.yml]\napplication: {}\n",
+		"cycle/b.yml":    "imports: [a.yml]\napplication: {}\n",
+		"missing.yml":    "imports: [nowhere.yml]\napplication: {}\n",
+		"conflict.yml":   "imports: [lib.yml]\napplication:\n    components:\n        db: {type: test.Other}\n",
+		"lib.yml":        "application:\n    components:\n        db: {type: test.Database}\n",
+		"broken.yml":     "imports: [bad.yml]\n",
+		"bad.yml":        "application:\n    bindings:\n        - [x]\n",
+		"not-a-list.yml": "imports: lib.yml\n",
+	})
+	defer os.RemoveAll(dir)
+	cases := map[string]string{
+		"cycle/a.yml":    "Import cycle: " + filepath.Join(dir, "cycle/a.yml") + " -> " + filepath.Join(dir, "cycle/b.yml") + " -> " + filepath.Join(dir, "cycle/a.yml"),
+		"missing.yml":    "Import " + filepath.Join(dir, "nowhere.yml") + ": ",
+		"conflict.yml":   "Component db: defined in " + filepath.Join(dir, "conflict.yml") + " and " + filepath.Join(dir, "lib.yml"),
+		"broken.yml":     filepath.Join(dir, "bad.yml") + ":3:11: Expected list of two for binding",
+		"not-a-list.yml": "cannot unmarshal",
+	}
+	for name, expected := range cases {
+		_, err := LoadFile(filepath.Join(dir, name))
+		if err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("%s:\nRaised: %v\nExpect: %v", name, err, expected)
+		}
+	}
+}
@@ -0,0 +1,510 @@
+package manifest
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/chemikadze/gonomi/manifest/datatype"
+	"github.com/chemikadze/gonomi/manifest/parsing"
+	"sort"
+	"strings"
+)
+
+// parses manifest written as JSON, result is the same as Parse
+// would give for YAML with the same structure
+func ParseJSON(manifest string) (Application, error) {
+	decoder := json.NewDecoder(strings.NewReader(manifest))
+	decoder.UseNumber()
+	var root applicationRootJSON
+	if err := decoder.Decode(&root); err != nil {
+		return Application{}, jsonError(manifest, err)
+	}
+	if decoder.More() {
+		return Application{}, parsing.ManifestError{"Unexpected data after manifest", 0, 0}
+	}
+	return parseApplication(root.Application.yaml())
+}
+
+// shape of manifest as decoded from JSON, see applicationRoot
+type applicationRootJSON struct {
+	Application applicationJSON `json:"application"`
+}
+
+type applicationJSON struct {
+	Interfaces map[string]map[string]interface{} `json:"interfaces"`
+	Components map[string]componentJSON          `json:"components"`
+	Bindings   [][]string                        `json:"bindings"`
+}
+
+type componentJSON struct {
+	Type          string                            `json:"type"`
+	Configuration map[string]interface{}            `json:"configuration"`
+	Interfaces    map[string]map[string]interface{} `json:"interfaces"`
+	Required      []interface{}                     `json:"required"`
+	Bindings      []interface{}                     `json:"bindings"`
+}
+
+// converts JSON values to what YAML decoder would produce
+func (a applicationJSON) yaml() application {
+	components := make(map[string]component, len(a.Components))
+	for name, c := range a.Components {
+		components[name] = component{c.Type, yamlMap(c.Configuration), yamlInterfaces(c.Interfaces), yamlValue(c.Required).([]interface{}), c.Bindings}
+	}
+	return application{yamlInterfaces(a.Interfaces), components, a.Bindings}
+}
+
+func yamlInterfaces(interfaces map[string]map[string]interface{}) map[interface{}]map[interface{}]interface{} {
+	if interfaces == nil {
+		return nil
+	}
+	result := make(map[interface{}]map[interface{}]interface{}, len(interfaces))
+	for name, pins := range interfaces {
+		result[name] = yamlMap(pins)
+	}
+	return result
+}
+
+func yamlMap(m map[string]interface{}) map[interface{}]interface{} {
+	if m == nil {
+		return nil
+	}
+	return yamlValue(m).(map[interface{}]interface{})
+}
+
+// decoder errors with position of offending value in manifest
+func jsonError(manifest string, err error) error {
+	offset := int64(0)
+	switch e := err.(type) {
+	case *json.SyntaxError:
+		offset = e.Offset
+	case *json.UnmarshalTypeError:
+		offset = e.Offset
+	default:
+		return err
+	}
+	// offset points after the offending character
+	line, column := position([]byte(manifest), int(offset)-1)
+	return parsing.ManifestError{err.Error(), line, column}
+}
+
+// encodes application in manifest format accepted by ParseJSON,
+// only leaf components in application can be represented
+func FormatJSON(app Application) ([]byte, error) {
+	components := make(map[string]interface{})
+	for name, c := range app.Components {
+		leaf, ok := c.(LeafComponent)
+		if !ok {
+			return nil, parsing.ManifestError{fmt.Sprintf("Component %s: composite components can't be represented in manifest", name), 0, 0}
+		}
+		component := map[string]interface{}{"type": leaf.Type.Name}
+		if len(leaf.Configuration) != 0 {
+			component["configuration"] = JSONValue(map[string]interface{}(leaf.Configuration))
+		}
+		interfaces := make(map[string]map[string]string)
+		required := []string{}
+		for ifaceName, iface := range leaf.Interfaces {
+			pins := make(map[string]string)
+			for pin, pinType := range iface.Pins {
+				pins[pin] = pinType.String()
+			}
+			interfaces[ifaceName] = pins
+			if iface.Required {
+				required = append(required, ifaceName)
+			}
+		}
+		if len(interfaces) != 0 {
+			component["interfaces"] = interfaces
+		}
+		if len(required) != 0 {
+			sort.Strings(required)
+			component["required"] = required
+		}
+		components[name] = component
+	}
+	application := make(map[string]interface{})
+	if len(app.Interfaces) != 0 {
+		interfaces := make(map[string]map[string]string)
+		for name, iface := range app.Interfaces {
+			pins := make(map[string]string)
+			for pin, binding := range iface {
+				pins[pin] = binding.String()
+			}
+			interfaces[name] = pins
+		}
+		application["interfaces"] = interfaces
+	}
+	if len(components) != 0 {
+		application["components"] = components
+	}
+	if len(app.Bindings) != 0 {
+		application["bindings"] = app.Bindings
+	}
+	return json.MarshalIndent(map[string]interface{}{"application": application}, "", "    ")
+}
+
+// decodes component encoded by json.Marshal
+func UnmarshalComponent(data []byte) (Component, error) {
+	var kind struct {
+		Kind string `json:"kind"`
+	}
+	if err := json.Unmarshal(data, &kind); err != nil {
+		return nil, err
+	}
+	switch kind.Kind {
+	case "leaf":
+		var c LeafComponent
+		err := json.Unmarshal(data, &c)
+		return c, err
+	case "composite":
+		var c CompositeComponent
+		err := json.Unmarshal(data, &c)
+		return c, err
+	}
+	return nil, errors.New(fmt.Sprintf("Unknown component kind: %s", kind.Kind))
+}
+
+func (t Type) MarshalJSON() ([]byte, error) {
+	return json.Marshal(t.Name)
+}
+
+func (t *Type) UnmarshalJSON(data []byte) error {
+	return json.Unmarshal(data, &t.Name)
+}
+
+func (c ComponentId) MarshalJSON() ([]byte, error) {
+	return json.Marshal(c.String())
+}
+
+func (c *ComponentId) UnmarshalJSON(data []byte) error {
+	var repr string
+	if err := json.Unmarshal(data, &repr); err != nil {
+		return err
+	}
+	// empty path is the application itself
+	if repr == "" {
+		*c = ComponentId{}
+		return nil
+	}
+	id, err := ParseComponentId(repr)
+	*c = id
+	return err
+}
+
+func (p PinId) MarshalJSON() ([]byte, error) {
+	return json.Marshal(pinIdJSON{p.Interface, p.Pin})
+}
+
+func (p *PinId) UnmarshalJSON(data []byte) error {
+	var repr pinIdJSON
+	err := json.Unmarshal(data, &repr)
+	*p = PinId{repr.Interface, repr.Pin}
+	return err
+}
+
+type pinIdJSON struct {
+	Interface string `json:"interface"`
+	Pin       string `json:"pin"`
+}
+
+func (c Configuration) MarshalJSON() ([]byte, error) {
+	if c == nil {
+		return []byte("null"), nil
+	}
+	return json.Marshal(JSONValue(map[string]interface{}(c)))
+}
+
+// values are decoded the same way as YAML parser does,
+// so decoded configuration is equal to the parsed one
+func (c *Configuration) UnmarshalJSON(data []byte) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var repr map[string]interface{}
+	if err := decoder.Decode(&repr); err != nil {
+		return err
+	}
+	if repr == nil {
+		*c = nil
+		return nil
+	}
+	*c = make(Configuration, len(repr))
+	for key, value := range repr {
+		(*c)[key] = yamlValue(value)
+	}
+	return nil
+}
+
+func (c LeafComponent) MarshalJSON() ([]byte, error) {
+	return json.Marshal(leafComponentJSON{"leaf", c.Type, c.Configuration, c.Interfaces})
+}
+
+func (c *LeafComponent) UnmarshalJSON(data []byte) error {
+	var repr leafComponentJSON
+	if err := json.Unmarshal(data, &repr); err != nil {
+		return err
+	}
+	*c = LeafComponent{repr.Type, repr.Configuration, repr.Interfaces}
+	return nil
+}
+
+type leafComponentJSON struct {
+	Kind          string                   `json:"kind"`
+	Type          Type                     `json:"type"`
+	Configuration Configuration            `json:"configuration"`
+	Interfaces    map[string]LeafInterface `json:"interfaces"`
+}
+
+func (c CompositeComponent) MarshalJSON() ([]byte, error) {
+	var components map[string]json.RawMessage
+	if c.Components != nil {
+		components = make(map[string]json.RawMessage, len(c.Components))
+		for name, child := range c.Components {
+			repr, err := json.Marshal(child)
+			if err != nil {
+				return nil, err
+			}
+			components[name] = repr
+		}
+	}
+	return json.Marshal(compositeComponentJSON{"composite", c.Type, c.Configuration, components, c.Interfaces, c.Bindings})
+}
+
+func (c *CompositeComponent) UnmarshalJSON(data []byte) error {
+	var repr compositeComponentJSON
+	if err := json.Unmarshal(data, &repr); err != nil {
+		return err
+	}
+	var components map[string]Component
+	if repr.Components != nil {
+		components = make(map[string]Component, len(repr.Components))
+		for name, raw := range repr.Components {
+			child, err := UnmarshalComponent(raw)
+			if err != nil {
+				return errors.New(fmt.Sprintf("Component %s: %s", name, err))
+			}
+			components[name] = child
+		}
+	}
+	*c = CompositeComponent{repr.Type, repr.Configuration, components, repr.Interfaces, repr.Bindings}
+	return nil
+}
+
+type compositeComponentJSON struct {
+	Kind          string                        `json:"kind"`
+	Type          Type                          `json:"type"`
+	Configuration Configuration                 `json:"configuration"`
+	Components    map[string]json.RawMessage    `json:"components"`
+	Interfaces    map[string]CompositeInterface `json:"interfaces"`
+	Bindings      []Binding                     `json:"bindings"`
+}
+
+func (p PinBinding) MarshalJSON() ([]byte, error) {
+	return json.Marshal(pinBindingJSON{p.TargetComponent, p.TargetPin})
+}
+
+func (p *PinBinding) UnmarshalJSON(data []byte) error {
+	var repr pinBindingJSON
+	err := json.Unmarshal(data, &repr)
+	*p = PinBinding{repr.Component, repr.Pin}
+	return err
+}
+
+type pinBindingJSON struct {
+	Component string `json:"component"`
+	Pin       PinId  `json:"pin"`
+}
+
+func (c ComponentBindingTarget) MarshalJSON() ([]byte, error) {
+	return json.Marshal(c.String())
+}
+
+func (c InterfaceBindingTarget) MarshalJSON() ([]byte, error) {
+	return json.Marshal(c.String())
+}
+
+// pair of targets as written in manifest, e.g. ["a", "b#i"]
+func (b Binding) MarshalJSON() ([]byte, error) {
+	return json.Marshal([2]BindingTarget{b.Left, b.Right})
+}
+
+func (b *Binding) UnmarshalJSON(data []byte) error {
+	var repr []string
+	if err := json.Unmarshal(data, &repr); err != nil {
+		return err
+	}
+	if len(repr) != 2 {
+		return parsing.ManifestError{fmt.Sprintf("Expected list of two for binding, got: %s", repr), 0, 0}
+	}
+	binding, err := parseBinding(repr[0], repr[1])
+	*b = binding
+	return err
+}
+
+func (i LeafInterface) MarshalJSON() ([]byte, error) {
+	return json.Marshal(leafInterfaceJSON{i.Pins, i.Required})
+}
+
+func (i *LeafInterface) UnmarshalJSON(data []byte) error {
+	var repr leafInterfaceJSON
+	err := json.Unmarshal(data, &repr)
+	*i = LeafInterface{repr.Pins, repr.Required}
+	return err
+}
+
+type leafInterfaceJSON struct {
+	Pins     map[string]DirectedPinType `json:"pins"`
+	Required bool                       `json:"required"`
+}
+
+func (d direction) MarshalJSON() ([]byte, error) {
+	if d.IsSend() {
+		return json.Marshal("send")
+	}
+	return json.Marshal("receive")
+}
+
+func (p DirectedPinType) MarshalJSON() ([]byte, error) {
+	return json.Marshal(directedPinTypeJSON{p.Direction, p.PinType})
+}
+
+func (p *DirectedPinType) UnmarshalJSON(data []byte) error {
+	var repr struct {
+		Direction string `json:"direction"`
+		Pin       struct {
+			Kind      string `json:"kind"`
+			Type      string `json:"type"`
+			Arguments string `json:"arguments"`
+			Progress  string `json:"progress"`
+			Result    string `json:"result"`
+		} `json:"pin"`
+	}
+	if err := json.Unmarshal(data, &repr); err != nil {
+		return err
+	}
+	var direction Direction
+	switch repr.Direction {
+	case "send":
+		direction = Sends
+	case "receive":
+		direction = Receives
+	default:
+		return parsing.ManifestError{fmt.Sprintf("Unknown pin direction: %s", repr.Direction), 0, 0}
+	}
+	var pinType PinType
+	switch repr.Pin.Kind {
+	case "signal", "configuration":
+		t, err := parseJSONDataType(repr.Pin.Type)
+		if err != nil {
+			return err
+		}
+		if repr.Pin.Kind == "signal" {
+			pinType = SignalPin{t}
+		} else {
+			pinType = ConfigurationPin{t}
+		}
+	case "command":
+		records := [3]datatype.Record{}
+		for i, body := range []string{repr.Pin.Arguments, repr.Pin.Progress, repr.Pin.Result} {
+			t, err := datatype.Parse("record<" + body + ">")
+			if err != nil {
+				return err
+			}
+			records[i] = t.(datatype.Record)
+		}
+		pinType = CommandPin{records[0], records[1], records[2]}
+	default:
+		return parsing.ManifestError{fmt.Sprintf("Unknown pin type: %s", repr.Pin.Kind), 0, 0}
+	}
+	*p = DirectedPinType{direction, pinType}
+	return nil
+}
+
+type directedPinTypeJSON struct {
+	Direction Direction `json:"direction"`
+	Pin       PinType   `json:"pin"`
+}
+
+func (s SignalPin) MarshalJSON() ([]byte, error) {
+	return json.Marshal(typedPinJSON{"signal", dataTypeName(s.DataType)})
+}
+
+func (s ConfigurationPin) MarshalJSON() ([]byte, error) {
+	return json.Marshal(typedPinJSON{"configuration", dataTypeName(s.DataType)})
+}
+
+type typedPinJSON struct {
+	Kind string `json:"kind"`
+	Type string `json:"type"`
+}
+
+func (s CommandPin) MarshalJSON() ([]byte, error) {
+	return json.Marshal(commandPinJSON{"command", s.Arguments.BodyName(), s.Progress.BodyName(), s.Result.BodyName()})
+}
+
+type commandPinJSON struct {
+	Kind      string `json:"kind"`
+	Arguments string `json:"arguments"`
+	Progress  string `json:"progress"`
+	Result    string `json:"result"`
+}
+
+// empty name stands for untyped pin
+func parseJSONDataType(repr string) (datatype.DataType, error) {
+	if repr == "" {
+		return nil, nil
+	}
+	return datatype.Parse(repr)
+}
+
+// converts decoded JSON value to the form YAML decoder produces
+func yamlValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		result := make(map[interface{}]interface{}, len(v))
+		for key, value := range v {
+			result[key] = yamlValue(value)
+		}
+		return result
+	case []interface{}:
+		result := make([]interface{}, len(v))
+		for i, value := range v {
+			result[i] = yamlValue(value)
+		}
+		return result
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			if int64(int(i)) == i {
+				return int(i)
+			}
+			return i
+		}
+		f, _ := v.Float64()
+		return f
+	}
+	return v
+}
+
+// converts YAML decoded value to the form JSON encoder accepts
+func JSONValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[interface{}]interface{}:
+		result := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			result[fmt.Sprint(key)] = JSONValue(value)
+		}
+		return result
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			result[key] = JSONValue(value)
+		}
+		return result
+	case []interface{}:
+		result := make([]interface{}, len(v))
+		for i, value := range v {
+			result[i] = JSONValue(value)
+		}
+		return result
+	}
+	return v
+}
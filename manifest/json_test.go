@@ -0,0 +1,145 @@
+package manifest
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const jsonTestManifest = `
+    application:
+        components:
+            x:
+                type: test.Component
+                configuration:
+                    size: 1
+                    ratio: 0.5
+                    nested: {a: [1, two]}
+                interfaces:
+                    i:
+                        p: publish-signal(list<string>)
+                        c: send-command(string a, int b)
+                        u: consume-signal()
+                required: [i]
+            y:
+                type: test.Component
+                interfaces:
+                    i:
+                        p: consume-signal(list<string>)
+                        c: receive-command(string a, int b)
+                        u: publish-signal()
+        bindings:
+            - [x, y#i]
+`
+
+func TestParseJSON(t *testing.T) {
+	parsed, err := Parse(jsonTestManifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	app, err := ParseJSON(`{"application": {
+        "components": {
+            "x": {
+                "type": "test.Component",
+                "configuration": {"size": 1, "ratio": 0.5, "nested": {"a": [1, "two"]}},
+                "interfaces": {"i": {
+                    "p": "publish-signal(list<string>)",
+                    "c": "send-command(string a, int b)",
+                    "u": "consume-signal()"
+                }},
+                "required": ["i"]
+            },
+            "y": {
+                "type": "test.Component",
+                "interfaces": {"i": {
+                    "p": "consume-signal(list<string>)",
+                    "c": "receive-command(string a, int b)",
+                    "u": "publish-signal()"
+                }}
+            }
+        },
+        "bindings": [["x", "y#i"]]
+    }}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(app, parsed) {
+		t.Errorf("\nJSON: %v\nYAML: %v", app, parsed)
+	}
+	formatted, err := FormatJSON(parsed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	app, err = ParseJSON(string(formatted))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(app, parsed) {
+		t.Errorf("\nFormatted: %s\nParsed:    %v\nExpect:    %v", formatted, app, parsed)
+	}
+}
+
+func TestParseJSONErrors(t *testing.T) {
+	for _, manifest := range []string{`{"application": `, `{} {}`, `{"application": {"bindings": [["x"]]}}`} {
+		if _, err := ParseJSON(manifest); err == nil {
+			t.Error("Error expected for", manifest)
+		}
+	}
+}
+
+func TestMarshalJSON(t *testing.T) {
+	parsed, err := Parse(jsonTestManifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, app := range []Application{parsed, walkApp} {
+		data, err := json.Marshal(app)
+		if err != nil {
+			t.Fatal(err)
+		}
+		again, err := json.Marshal(app)
+		if err != nil || string(again) != string(data) {
+			t.Error("Encoding is not stable:", string(data), string(again))
+		}
+		var decoded Application
+		if err := json.Unmarshal(data, &decoded); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(decoded, app) {
+			t.Errorf("\nDecoded: %v\nExpect:  %v\nJSON:    %s", decoded, app, data)
+		}
+	}
+}
+
+func TestMarshalPinJSON(t *testing.T) {
+	pin, err := ParsePinType("send-command(string a, int b)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(pin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"direction":"send","pin":{"kind":"command","arguments":"string a, int b","progress":"","result":""}}`
+	if string(data) != expected {
+		t.Errorf("\nEncoded: %s\nExpect:  %s", data, expected)
+	}
+	if _, err := UnmarshalComponent([]byte(`{"kind": "unknown"}`)); err == nil || !strings.Contains(err.Error(), "unknown") {
+		t.Error("Unknown kind error expected, got", err)
+	}
+}
+
+func TestParseJSONErrorPosition(t *testing.T) {
+	cases := map[string]string{
+		"{\"application\": {\n    \"components\": [\"x\"]}}":                "2:19: json: cannot unmarshal array into Go struct field applicationRootJSON.application.components of type map[string]manifest.componentJSON",
+		"{\"application\": {\n    \"components\": {\"x\": {\"type\": 1}}}}": "2:34: json: cannot unmarshal number into Go struct field applicationRootJSON.application.components.x.type of type string",
+		"{\"application\":\n    [}":                                         "2:6: invalid character '}' looking for beginning of value",
+	}
+	for manifest, expected := range cases {
+		_, err := ParseJSON(manifest)
+		if err == nil || err.Error() != expected {
+			t.Errorf("\nRaised: %v\nExpect: %v", err, expected)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+// Package lint reports constructs of application which are valid
+// but likely to be mistakes, e.g. constants nobody consumes or pins
+// left unbound. Intentional cases are suppressed by comments in manifest:
+//
+//	web:
+//	    interfaces:
+//	        # lint:ignore unbound-interface used by operators only
+//	        debug:
+//	            trace: publish-signal(string)
+//	        out:
+//	            status: publish-signal(string) # lint:ignore unused-pin
+//
+// comment applies to component, interface or pin it is attached to,
+// "all" suppresses every rule.
+package lint
+
+import (
+	"github.com/chemikadze/gonomi/manifest"
+	"github.com/chemikadze/gonomi/manifest/catalog"
+	"github.com/chemikadze/gonomi/manifest/constants"
+	"sort"
+)
+
+type Finding struct {
+	Rule      string
+	Component manifest.ComponentId
+	// pin or interface finding is about, empty for whole component
+	Pin     manifest.PinId
+	Message string
+}
+
+func (f Finding) String() string {
+	return f.Component.String() + ": " + f.Message + " [" + f.Rule + "]"
+}
+
+// rules get flattened graph of application and usage of its pins,
+// which is computed once for all of them
+type Rule struct {
+	Name  string
+	Check func(graph manifest.Graph, u usage) []Finding
+}
+
+var Rules = []Rule{
+	{"component-type", componentTypes},
+	{"constant-value", constantValues},
+	{"unbound-constant", unboundConstants},
+	{"unused-pin", unusedPins},
+	{"unbound-interface", unboundInterfaces},
+	{"missing-producer", missingProducers},
+	{"missing-receiver", missingReceivers},
+}
+
+// runs every rule, findings are ordered by component and rule
+func Lint(app manifest.Application) ([]Finding, error) {
+	graph, err := manifest.Flatten(app)
+	if err != nil {
+		return nil, err
+	}
+	u := newUsage(app, graph)
+	findings := []Finding{}
+	for _, rule := range Rules {
+		findings = append(findings, rule.Check(graph, u)...)
+	}
+	sort.SliceStable(findings, func(i, j int) bool {
+		return findings[i].Component.String() < findings[j].Component.String()
+	})
+	return findings, nil
+}
+
+// runs every rule on application of manifest and reports services
+// and policies referring to unknown components as section-reference
+func LintManifest(m manifest.Manifest) ([]Finding, error) {
+	findings, err := Lint(m.Application)
+	if err != nil {
+		return nil, err
+	}
+	for _, problem := range m.Validate() {
+		problem := problem.(manifest.SectionError)
+		var id manifest.ComponentId
+		if problem.Section == "policies" {
+			id = m.Policies[problem.Name].Components[problem.Index]
+		} else {
+			id = manifest.BindingTargetComponent(m.Services[problem.Name].Bindings[problem.Index])
+		}
+		findings = append(findings, Finding{"section-reference", id, manifest.PinId{}, problem.Error()})
+	}
+	sort.SliceStable(findings, func(i, j int) bool {
+		return findings[i].Component.String() < findings[j].Component.String()
+	})
+	return findings, nil
+}
+
+// components which don't follow descriptors of their types in built-in catalog
+func componentTypes(graph manifest.Graph, u usage) []Finding {
+	findings := []Finding{}
+	types := catalog.Builtin()
+	for _, c := range graph.Components {
+		for _, problem := range types.Validate(c.Component) {
+			findings = append(findings, Finding{"component-type", c.Id, manifest.PinId{}, problem.Error()})
+		}
+	}
+	return findings
+}
+
+// values of constants which don't match their pins
+func constantValues(graph manifest.Graph, u usage) []Finding {
+	findings := []Finding{}
+	for _, c := range graph.Components {
+		if c.Component.Type.Name != constants.TypeName {
+			continue
+		}
+		for _, problem := range constants.Validate(c.Component) {
+			findings = append(findings, Finding{"constant-value", c.Id, manifest.PinId{}, problem.Error()})
+		}
+	}
+	return findings
+}
+
+// publish-signal pins of constants which are not bound to any consumer
+func unboundConstants(graph manifest.Graph, u usage) []Finding {
+	findings := []Finding{}
+	for _, c := range graph.Components {
+		if c.Component.Type.Name != constants.TypeName {
+			continue
+		}
+		for _, pin := range publishedPins(c.Component) {
+			if !u.used(c.Id, pin, u.from) {
+				findings = append(findings, Finding{"unbound-constant", c.Id, pin, "Constant " + pin.Interface + "." + pin.Pin + " is never bound"})
+			}
+		}
+	}
+	return findings
+}
+
+// publish-signal pins of component sorted by interface and pin
+func publishedPins(c manifest.LeafComponent) []manifest.PinId {
+	pins := []manifest.PinId{}
+	for name, iface := range c.Interfaces {
+		for pin, pinType := range iface.Pins {
+			if _, ok := pinType.PinType.(manifest.SignalPin); ok && pinType.Direction.IsSend() {
+				pins = append(pins, manifest.PinId{name, pin})
+			}
+		}
+	}
+	sortPins(pins)
+	return pins
+}
@@ -0,0 +1,195 @@
+package lint
+
+import (
+	"github.com/chemikadze/gonomi/manifest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func lint(t *testing.T, src string) []string {
+	app, err := manifest.Parse(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	findings, err := Lint(app)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := []string{}
+	for _, f := range findings {
+		result = append(result, f.String())
+	}
+	return result
+}
+
+func TestConstants(t *testing.T) {
+	findings := lint(t, `
+application:
+    components:
+        constants:
+            type: cobalt.common.Constants
+            configuration:
+                result.port: 80
+                result.host: 1
+            interfaces:
+                result:
+                    port: publish-signal(int)
+                    host: publish-signal(string)
+        web:
+            type: test.Web
+            interfaces:
+                result:
+                    port: consume-signal(int)
+    bindings:
+        - [constants, web]
+`)
+	expected := []string{
+		"constants: Constant result.host: Expected string, got int [constant-value]",
+		"constants: Constant result.host is never bound [unbound-constant]",
+	}
+	if !reflect.DeepEqual(findings, expected) {
+		t.Errorf("\nFindings: %q\nExpect:   %q", findings, expected)
+	}
+}
+
+func TestComponentTypes(t *testing.T) {
+	findings := lint(t, `
+application:
+    components:
+        constants:
+            type: cobalt.common.Constants
+            configuration:
+                out.url: http://web
+            interfaces:
+                out:
+                    url: publish-signal(string)
+                    reset: receive-command()
+        workflow:
+            type: workflow.Instance
+            interfaces:
+                out:
+                    url: consume-signal(string)
+    bindings:
+        - [constants, workflow]
+`)
+	expected := []string{
+		"constants: cobalt.common.Constants doesn't allow receive-command pin out.reset [component-type]",
+		"constants: Constant out.reset: receive-command() is not a publish-signal pin [constant-value]",
+		"workflow: workflow.Instance requires configuration configuration.workflows [component-type]",
+	}
+	if !reflect.DeepEqual(findings, expected) {
+		t.Errorf("\nFindings: %q\nExpect:   %q", findings, expected)
+	}
+}
+
+const deadPins = `
+application:
+    interfaces:
+        api:
+            status: bind(web#out.status)
+    components:
+        web:
+            type: test.Web
+            interfaces:
+                out:
+                    status: publish-signal(string)
+                    hits: publish-signal(int)
+                    double: send-command(int x)
+                in:
+                    config: consume-signal(string)
+                debug:
+                    trace: publish-signal(string)
+            required: [in]
+        sink:
+            type: test.Sink
+            interfaces:
+                out:
+                    hits: consume-signal(int)
+                    missing: consume-signal(string)
+    bindings:
+        - [web, sink]
+`
+
+func TestDeadPins(t *testing.T) {
+	findings := lint(t, deadPins)
+	expected := []string{
+		"sink: Pin out.missing has no producer [missing-producer]",
+		"web: Interface debug is not bound [unbound-interface]",
+		"web: Pin in.config has no producer [missing-producer]",
+		"web: Command out.double has no receiver [missing-receiver]",
+	}
+	if !reflect.DeepEqual(findings, expected) {
+		t.Errorf("\nFindings: %q\nExpect:   %q", findings, expected)
+	}
+}
+
+func TestSuppressions(t *testing.T) {
+	src := strings.Replace(deadPins, `
+                debug:`, `
+                # lint:ignore unbound-interface used by operators only
+                debug:`, 1)
+	src = strings.Replace(src, "double: send-command(int x)", "double: send-command(int x) # lint:ignore missing-receiver,unused-pin", 1)
+	src = strings.Replace(src, "        sink:\n", "        # lint:ignore all\n        sink:\n", 1)
+	app, err := manifest.Parse(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	findings, err := Lint(app)
+	if err != nil {
+		t.Fatal(err)
+	}
+	suppressions, err := ParseSuppressions(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := []string{}
+	for _, f := range suppressions.Filter(findings) {
+		result = append(result, f.String())
+	}
+	expected := []string{"web: Pin in.config has no producer [missing-producer]"}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("\nFindings: %q\nExpect:   %q", result, expected)
+	}
+}
+
+func TestLintManifest(t *testing.T) {
+	m, err := manifest.ParseManifest(`
+application:
+    components:
+        db:
+            type: test.Database
+            interfaces:
+                keys:
+                    key: consume-signal(string)
+                    ready: publish-signal(string)
+services:
+    keystore:
+        type: environment.KeyStore
+        bindings: [db#keys, cache, db#admin]
+policies:
+    scaling:
+        type: environment.AutoScaling
+        components: [db, web]
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	findings, err := LintManifest(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := []string{}
+	for _, f := range findings {
+		result = append(result, f.String())
+	}
+	expected := []string{
+		"cache: Service keystore: Component cache: no component cache in application [section-reference]",
+		"db: Interface keys is not bound [unbound-interface]",
+		"db: Service keystore: Component db: no interface admin [section-reference]",
+		"web: Policy scaling: Component web: no component web in application [section-reference]",
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("\nFindings: %q\nExpect:   %q", result, expected)
+	}
+}
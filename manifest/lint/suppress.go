@@ -0,0 +1,127 @@
+package lint
+
+import (
+	"github.com/chemikadze/gonomi/manifest"
+	"gopkg.in/yaml.v3"
+	"strings"
+)
+
+const (
+	ignoreDirective = "lint:ignore"
+	// suppresses every rule
+	AllRules = "all"
+)
+
+// rules suppressed by scope: component path, component#interface
+// or component#interface.pin
+type Suppressions map[string][]string
+
+// collects lint:ignore comments of components, interfaces and pins
+// of the first document of manifest
+func ParseSuppressions(src string) (Suppressions, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(src), &doc); err != nil {
+		return nil, err
+	}
+	s := Suppressions{}
+	if len(doc.Content) == 0 {
+		return s, nil
+	}
+	s.components(manifest.ComponentId{}, mappingValue(mappingValue(doc.Content[0], "application"), "components"))
+	return s, nil
+}
+
+func (s Suppressions) components(parent manifest.ComponentId, node *yaml.Node) {
+	eachPair(node, func(key, component *yaml.Node) {
+		id := parent.Child(key.Value)
+		s.add(id.String(), key, component)
+		eachPair(mappingValue(component, "interfaces"), func(key, iface *yaml.Node) {
+			scope := interfaceKey(id, key.Value)
+			s.add(scope, key, iface)
+			eachPair(iface, func(key, pin *yaml.Node) {
+				s.add(scope+"."+key.Value, key, pin)
+			})
+		})
+		// nested components of composites
+		s.components(id, mappingValue(component, "components"))
+	})
+}
+
+// comments before key, after it or after scalar value
+func (s Suppressions) add(scope string, key *yaml.Node, value *yaml.Node) {
+	comments := []string{key.HeadComment, key.LineComment}
+	if value.Kind == yaml.ScalarNode {
+		comments = append(comments, value.LineComment)
+	}
+	for _, comment := range comments {
+		for _, line := range strings.Split(comment, "\n") {
+			line = strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(line), "#"))
+			if !strings.HasPrefix(line, ignoreDirective+" ") {
+				continue
+			}
+			// rules are comma-separated, the rest of comment is the reason
+			fields := strings.Fields(line[len(ignoreDirective):])
+			for _, rule := range strings.Split(fields[0], ",") {
+				if rule != "" {
+					s[scope] = append(s[scope], rule)
+				}
+			}
+		}
+	}
+}
+
+// whether rule of finding is suppressed for its pin, interface, component
+// or any composite containing the component
+func (s Suppressions) Suppressed(f Finding) bool {
+	scopes := []string{}
+	for i := 1; i <= len(f.Component.Path); i++ {
+		scopes = append(scopes, manifest.ComponentId{f.Component.Path[:i]}.String())
+	}
+	if f.Pin.Interface != "" {
+		scopes = append(scopes, interfaceKey(f.Component, f.Pin.Interface))
+		if f.Pin.Pin != "" {
+			scopes = append(scopes, interfaceKey(f.Component, f.Pin.Interface)+"."+f.Pin.Pin)
+		}
+	}
+	for _, scope := range scopes {
+		for _, rule := range s[scope] {
+			if rule == f.Rule || rule == AllRules {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// findings which are not suppressed
+func (s Suppressions) Filter(findings []Finding) []Finding {
+	result := []Finding{}
+	for _, f := range findings {
+		if !s.Suppressed(f) {
+			result = append(result, f)
+		}
+	}
+	return result
+}
+
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	var result *yaml.Node
+	eachPair(node, func(k, v *yaml.Node) {
+		if k.Value == key {
+			result = v
+		}
+	})
+	return result
+}
+
+func eachPair(node *yaml.Node, f func(key, value *yaml.Node)) {
+	for node != nil && node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	if node == nil || node.Kind != yaml.MappingNode {
+		return
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		f(node.Content[i], node.Content[i+1])
+	}
+}
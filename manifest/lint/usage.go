@@ -0,0 +1,148 @@
+package lint
+
+import (
+	"github.com/chemikadze/gonomi/manifest"
+	"github.com/chemikadze/gonomi/manifest/constants"
+	"sort"
+)
+
+// which pins of flattened application take part in connections
+type usage struct {
+	// endpoints sending along connections
+	from map[string]bool
+	// endpoints receiving along connections
+	to map[string]bool
+	// endpoints re-exported by composites, they are used outside
+	exported map[string]bool
+	// component#interface having any used pin
+	interfaces map[string]bool
+}
+
+func newUsage(app manifest.Application, graph manifest.Graph) usage {
+	u := usage{map[string]bool{}, map[string]bool{}, map[string]bool{}, map[string]bool{}}
+	for _, connection := range graph.Connections {
+		u.from[connection.From.String()] = true
+		u.to[connection.To.String()] = true
+		u.interfaces[interfaceKey(connection.From.Component, connection.From.Pin.Interface)] = true
+		u.interfaces[interfaceKey(connection.To.Component, connection.To.Pin.Interface)] = true
+	}
+	manifest.Walk(app, manifest.VisitorFuncs{
+		OnCompositeInterface: func(id manifest.ComponentId, name string, iface manifest.CompositeInterface) manifest.WalkAction {
+			for _, binding := range iface {
+				target := id
+				if binding.TargetComponent != "" {
+					child, err := manifest.ParseComponentId(binding.TargetComponent)
+					if err != nil {
+						continue
+					}
+					target = manifest.ComponentId{append(append([]string{}, id.Path...), child.Path...)}
+				}
+				u.exported[manifest.Endpoint{target, binding.TargetPin}.String()] = true
+				u.interfaces[interfaceKey(target, binding.TargetPin.Interface)] = true
+			}
+			return manifest.Continue
+		},
+	})
+	return u
+}
+
+func interfaceKey(id manifest.ComponentId, iface string) string {
+	return id.String() + "#" + iface
+}
+
+// pins of interfaces which are bound at all or required, pins of optional
+// interfaces nobody binds are reported as unbound-interface instead
+func (u usage) boundPins(c manifest.FlatComponent) []manifest.PinId {
+	pins := []manifest.PinId{}
+	for name, iface := range c.Component.Interfaces {
+		if !iface.Required && !u.interfaces[interfaceKey(c.Id, name)] {
+			continue
+		}
+		for pin := range iface.Pins {
+			pins = append(pins, manifest.PinId{name, pin})
+		}
+	}
+	sortPins(pins)
+	return pins
+}
+
+func (u usage) used(id manifest.ComponentId, pin manifest.PinId, byConnection map[string]bool) bool {
+	endpoint := manifest.Endpoint{id, pin}.String()
+	return byConnection[endpoint] || u.exported[endpoint]
+}
+
+// publish-signal pins nobody consumes, constants are reported by unbound-constant
+func unusedPins(graph manifest.Graph, u usage) []Finding {
+	findings := []Finding{}
+	for _, c := range graph.Components {
+		if c.Component.Type.Name == constants.TypeName {
+			continue
+		}
+		for _, pin := range u.boundPins(c) {
+			pinType := c.Component.Interfaces[pin.Interface].Pins[pin.Pin]
+			if _, ok := pinType.PinType.(manifest.SignalPin); ok && pinType.Direction.IsSend() && !u.used(c.Id, pin, u.from) {
+				findings = append(findings, Finding{"unused-pin", c.Id, pin, "Pin " + pin.Interface + "." + pin.Pin + " is never consumed"})
+			}
+		}
+	}
+	return findings
+}
+
+// optional interfaces none of whose pins are bound or re-exported,
+// constants are reported by unbound-constant
+func unboundInterfaces(graph manifest.Graph, u usage) []Finding {
+	findings := []Finding{}
+	for _, c := range graph.Components {
+		if c.Component.Type.Name == constants.TypeName {
+			continue
+		}
+		names := []string{}
+		for name, iface := range c.Component.Interfaces {
+			if !iface.Required && !u.interfaces[interfaceKey(c.Id, name)] {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			findings = append(findings, Finding{"unbound-interface", c.Id, manifest.PinId{Interface: name}, "Interface " + name + " is not bound"})
+		}
+	}
+	return findings
+}
+
+// consume-signal pins no publisher is bound to
+func missingProducers(graph manifest.Graph, u usage) []Finding {
+	findings := []Finding{}
+	for _, c := range graph.Components {
+		for _, pin := range u.boundPins(c) {
+			pinType := c.Component.Interfaces[pin.Interface].Pins[pin.Pin]
+			if _, ok := pinType.PinType.(manifest.SignalPin); ok && pinType.Direction.IsReceive() && !u.used(c.Id, pin, u.to) {
+				findings = append(findings, Finding{"missing-producer", c.Id, pin, "Pin " + pin.Interface + "." + pin.Pin + " has no producer"})
+			}
+		}
+	}
+	return findings
+}
+
+// send-command pins no receiver is bound to
+func missingReceivers(graph manifest.Graph, u usage) []Finding {
+	findings := []Finding{}
+	for _, c := range graph.Components {
+		for _, pin := range u.boundPins(c) {
+			pinType := c.Component.Interfaces[pin.Interface].Pins[pin.Pin]
+			if _, ok := pinType.PinType.(manifest.CommandPin); ok && pinType.Direction.IsSend() && !u.used(c.Id, pin, u.from) {
+				findings = append(findings, Finding{"missing-receiver", c.Id, pin, "Command " + pin.Interface + "." + pin.Pin + " has no receiver"})
+			}
+		}
+	}
+	return findings
+}
+
+func sortPins(pins []manifest.PinId) {
+	sort.Slice(pins, func(i, j int) bool {
+		if pins[i].Interface != pins[j].Interface {
+			return pins[i].Interface < pins[j].Interface
+		}
+		return pins[i].Pin < pins[j].Pin
+	})
+}
@@ -0,0 +1,142 @@
+package manifest
+
+import (
+	"fmt"
+	"github.com/chemikadze/gonomi/manifest/parsing"
+	"strings"
+)
+
+// failed resolution of component path, Segment is index of path element
+// which could not be resolved or -1 if path itself is fine
+type LookupError struct {
+	Id      ComponentId
+	Segment int
+	Message string
+}
+
+func (e LookupError) Error() string {
+	return e.Message
+}
+
+// dotted representation as used in bindings, e.g. a.b.c
+func (c ComponentId) String() string {
+	return strings.Join(c.Path, ".")
+}
+
+func (c ComponentId) Child(name string) ComponentId {
+	return ComponentId{append(append([]string{}, c.Path...), name)}
+}
+
+func ParseComponentId(repr string) (ComponentId, error) {
+	path := strings.Split(repr, ".")
+	for _, segment := range path {
+		if segment == "" {
+			return ComponentId{}, parsing.ManifestError{fmt.Sprintf("Malformed component path: %s", repr), 0, 0}
+		}
+	}
+	return ComponentId{path}, nil
+}
+
+func (c ComponentBindingTarget) String() string {
+	return c.Component.String()
+}
+
+func (c InterfaceBindingTarget) String() string {
+	return c.Component.String() + "#" + c.Interface
+}
+
+// parses binding side like a.b or a.b#iface
+func ParseBindingTarget(repr string) (BindingTarget, error) {
+	splitted := strings.Split(repr, "#")
+	if len(splitted) > 2 || (len(splitted) == 2 && splitted[1] == "") {
+		return nil, parsing.ManifestError{fmt.Sprintf("Unexpected binding target: %s", repr), 0, 0}
+	}
+	id, err := ParseComponentId(splitted[0])
+	if err != nil {
+		return nil, parsing.ManifestError{fmt.Sprintf("Unexpected binding target: %s", repr), 0, 0}
+	}
+	if len(splitted) == 1 {
+		return ComponentBindingTarget{id}, nil
+	}
+	return InterfaceBindingTarget{id, splitted[1]}, nil
+}
+
+func FormatBindingTarget(target BindingTarget) string {
+	if s, ok := target.(fmt.Stringer); ok {
+		return s.String()
+	}
+	return fmt.Sprint(target)
+}
+
+// component of binding target
+func BindingTargetComponent(target BindingTarget) ComponentId {
+	switch target := target.(type) {
+	case ComponentBindingTarget:
+		return target.Component
+	case InterfaceBindingTarget:
+		return target.Component
+	}
+	return ComponentId{}
+}
+
+// finds component by path from application root, empty path is the root itself
+func (a Application) Lookup(id ComponentId) (Component, error) {
+	var current Component = a.CompositeComponent
+	for i, segment := range id.Path {
+		composite, ok := current.(CompositeComponent)
+		if !ok {
+			return nil, LookupError{id, i, fmt.Sprintf("Component %s: %s is not a composite", id, ComponentId{id.Path[:i]})}
+		}
+		child, ok := composite.Components[segment]
+		if !ok {
+			parent := "application"
+			if i > 0 {
+				parent = ComponentId{id.Path[:i]}.String()
+			}
+			return nil, LookupError{id, i, fmt.Sprintf("Component %s: no component %s in %s", id, segment, parent)}
+		}
+		current = child
+	}
+	return current, nil
+}
+
+// finds interface of component, interfaces of composite components are resolved
+// into pins of children they re-export
+func (a Application) LookupInterface(id ComponentId, name string) (LeafInterface, error) {
+	c, err := a.Lookup(id)
+	if err != nil {
+		return LeafInterface{}, err
+	}
+	switch c := c.(type) {
+	case LeafComponent:
+		iface, ok := c.Interfaces[name]
+		if !ok {
+			return iface, LookupError{id, -1, fmt.Sprintf("Component %s: no interface %s", id, name)}
+		}
+		return iface, nil
+	case CompositeComponent:
+		iface, ok := c.Interfaces[name]
+		if !ok {
+			return LeafInterface{}, LookupError{id, -1, fmt.Sprintf("Component %s: no interface %s", id, name)}
+		}
+		result := LeafInterface{Pins: make(map[string]DirectedPinType)}
+		for pin := range iface {
+			pinType, err := a.LookupPin(id, PinId{name, pin})
+			if err != nil {
+				return LeafInterface{}, err
+			}
+			result.Pins[pin] = pinType
+		}
+		return result, nil
+	}
+	return LeafInterface{}, LookupError{id, -1, fmt.Sprintf("Component %s: unsupported component %T", id, c)}
+}
+
+// finds pin type, pins of composite components are resolved through re-exports
+func (a Application) LookupPin(id ComponentId, pin PinId) (DirectedPinType, error) {
+	resolved, err := a.resolvePin(id, pin, nil)
+	if err != nil {
+		return DirectedPinType{}, err
+	}
+	return resolved.PinType, nil
+}
@@ -0,0 +1,111 @@
+package manifest
+
+import (
+	"github.com/chemikadze/gonomi/manifest/datatype"
+	"reflect"
+	"testing"
+)
+
+func TestParseBindingTarget(t *testing.T) {
+	cases := map[string]BindingTarget{
+		"a":       ComponentBindingTarget{ComponentId{[]string{"a"}}},
+		"a.b.c":   ComponentBindingTarget{ComponentId{[]string{"a", "b", "c"}}},
+		"a.b#i":   InterfaceBindingTarget{ComponentId{[]string{"a", "b"}}, "i"},
+		"a#i.j.k": InterfaceBindingTarget{ComponentId{[]string{"a"}}, "i.j.k"},
+	}
+	for repr, expected := range cases {
+		target, err := ParseBindingTarget(repr)
+		if err != nil {
+			t.Error(err)
+		}
+		if !reflect.DeepEqual(target, expected) {
+			t.Errorf("\nParsed: %v\nExpect: %v", target, expected)
+		}
+		if FormatBindingTarget(target) != repr {
+			t.Errorf("\nFormatted: %v\nExpect:    %v", FormatBindingTarget(target), repr)
+		}
+	}
+	for _, repr := range []string{"", "a..b", "a#", "#i", "a#b#c"} {
+		if _, err := ParseBindingTarget(repr); err == nil {
+			t.Error("Error expected for", repr)
+		}
+	}
+}
+
+func TestLookup(t *testing.T) {
+	c, err := walkApp.Lookup(ComponentId{[]string{"b", "c"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.GetType().Name != "test.Component" {
+		t.Error("Unexpected component", c)
+	}
+	root, err := walkApp.Lookup(ComponentId{})
+	if err != nil || !reflect.DeepEqual(root, walkApp.CompositeComponent) {
+		t.Error("Empty path should resolve to application", root, err)
+	}
+}
+
+func TestLookupError(t *testing.T) {
+	cases := map[string]int{
+		"x":     0,
+		"b.x":   1,
+		"a.x.y": 1,
+	}
+	for path, segment := range cases {
+		id, _ := ParseComponentId(path)
+		_, err := walkApp.Lookup(id)
+		lookupErr, ok := err.(LookupError)
+		if !ok {
+			t.Errorf("%s: LookupError expected, got %v", path, err)
+			continue
+		}
+		if lookupErr.Segment != segment {
+			t.Errorf("%s: expected failure at segment %d, got %d: %s", path, segment, lookupErr.Segment, err)
+		}
+	}
+	_, err := walkApp.Lookup(ComponentId{[]string{"b", "x"}})
+	if err.Error() != "Component b.x: no component x in b" {
+		t.Error("Unexpected message:", err)
+	}
+}
+
+func TestLookupPin(t *testing.T) {
+	pin, err := walkApp.LookupPin(ComponentId{[]string{"a"}}, PinId{"i", "p"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(pin, DirectedPinType{Sends, SignalPin{datatype.String{}}}) {
+		t.Error("Unexpected pin", pin)
+	}
+	if _, err := walkApp.LookupPin(ComponentId{[]string{"a"}}, PinId{"i", "x"}); err == nil {
+		t.Error("Error expected for unknown pin")
+	}
+	// composite re-exports pin of child which has no interfaces
+	if _, err := walkApp.LookupInterface(ComponentId{[]string{"b"}}, "e"); err == nil {
+		t.Error("Error expected for dangling re-export")
+	}
+}
+
+func TestLookupReexport(t *testing.T) {
+	app := Application{CompositeComponent{Components: map[string]Component{
+		"outer": CompositeComponent{
+			Components: map[string]Component{
+				"inner": LeafComponent{Interfaces: map[string]LeafInterface{
+					"i": LeafInterface{Pins: map[string]DirectedPinType{"p": {Sends, SignalPin{datatype.Int{}}}}},
+				}},
+			},
+			Interfaces: map[string]CompositeInterface{
+				"e": CompositeInterface{"q": PinBinding{"inner", PinId{"i", "p"}}},
+			},
+		},
+	}}}
+	iface, err := app.LookupInterface(ComponentId{[]string{"outer"}}, "e")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := LeafInterface{Pins: map[string]DirectedPinType{"q": {Sends, SignalPin{datatype.Int{}}}}}
+	if !reflect.DeepEqual(iface, expected) {
+		t.Errorf("\nResolved: %v\nExpect:   %v", iface, expected)
+	}
+}
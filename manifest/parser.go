@@ -15,8 +15,9 @@ type applicationRoot struct {
 }
 
 type application struct {
-	Components map[string]component `yaml:components`
-	Bindings   [][]string           `yaml:bindings`
+	Interfaces map[interface{}]map[interface{}]interface{} `yaml:interfaces`
+	Components map[string]component                        `yaml:components`
+	Bindings   [][]string                                  `yaml:bindings`
 }
 
 type component struct {
@@ -35,12 +36,20 @@ func Parse(manifest string) (Application, error) {
 	if err != nil {
 		return Application{}, err
 	}
-	components, err := parseComponents(m.Application.Components)
+	return parseApplication(m.Application)
+}
+
+func parseApplication(app application) (Application, error) {
+	components, err := parseComponents(app.Components)
+	if err != nil {
+		return Application{}, err
+	}
+	interfaces, err := parseCompositeInterfaces(app.Interfaces)
 	if err != nil {
 		return Application{}, err
 	}
-	bindings, err := parseBindings(m.Application.Bindings)
-	return Application{CompositeComponent{Components: components, Bindings: bindings}}, err
+	bindings, err := parseBindings(app.Bindings)
+	return Application{CompositeComponent{Components: components, Interfaces: interfaces, Bindings: bindings}}, err
 }
 
 func parseComponents(components map[string]component) (map[string]Component, error) {
@@ -115,51 +124,125 @@ func parseLeafInterface(original map[interface{}]interface{}) (LeafInterface, er
 	return LeafInterface{result, false}, nil
 }
 
+// interfaces exported by application, pins are bound to pins of components
+func parseCompositeInterfaces(original map[interface{}]map[interface{}]interface{}) (map[string]CompositeInterface, error) {
+	if len(original) == 0 {
+		return nil, nil
+	}
+	result := make(map[string]CompositeInterface)
+	for k, v := range original {
+		name, ok := k.(string)
+		if !ok {
+			continue
+		}
+		iface := CompositeInterface{}
+		for pin, repr := range v {
+			pin, ok := pin.(string)
+			if !ok {
+				continue
+			}
+			repr, ok := repr.(string)
+			if !ok {
+				continue
+			}
+			binding, err := ParsePinBinding(repr)
+			if err != nil {
+				return nil, err
+			}
+			iface[pin] = binding
+		}
+		result[name] = iface
+	}
+	return result, nil
+}
+
+// parses re-export like bind(component#interface.pin)
+func ParsePinBinding(repr string) (PinBinding, error) {
+	repr = strings.TrimSpace(repr)
+	if !strings.HasPrefix(repr, "bind(") || !strings.HasSuffix(repr, ")") {
+		return PinBinding{}, parsing.ManifestError{fmt.Sprintf("Malformed pin binding: %s", repr), 0, 0}
+	}
+	target := strings.TrimSpace(repr[len("bind(") : len(repr)-1])
+	parts := strings.Split(target, "#")
+	dot := strings.LastIndex(target, ".")
+	if len(parts) != 2 || parts[0] == "" || dot < len(parts[0]) || dot == len(parts[0])+1 || dot == len(target)-1 {
+		return PinBinding{}, parsing.ManifestError{fmt.Sprintf("Malformed pin binding: %s", repr), 0, 0}
+	}
+	return PinBinding{parts[0], PinId{target[len(parts[0])+1 : dot], target[dot+1:]}}, nil
+}
+
+func (p PinBinding) String() string {
+	return "bind(" + p.TargetComponent + "#" + p.TargetPin.Interface + "." + p.TargetPin.Pin + ")"
+}
+
+// parses pin declaration like publish-signal(string)
+func ParsePinType(repr string) (DirectedPinType, error) {
+	return parseDirectedPinType(repr)
+}
+
 func parseDirectedPinType(repr string) (DirectedPinType, error) {
+	repr = strings.TrimSpace(repr)
 	pinAndTypes := strings.SplitN(repr, "(", 2)
+	if len(pinAndTypes) != 2 || !strings.HasSuffix(pinAndTypes[1], ")") {
+		return DirectedPinType{}, parsing.ManifestError{fmt.Sprintf("Malformed pin type: %s", repr), 0, 0}
+	}
+	pinAndTypes[0] = strings.TrimSpace(pinAndTypes[0])
 	pinAndTypes[1] = pinAndTypes[1][:len(pinAndTypes[1])-1]
 	switch pinAndTypes[0] {
 	case "publish-signal":
-		t, _ := datatype.Parse(pinAndTypes[1])
-		return DirectedPinType{Sends, SignalPin{t}}, nil
+		t, err := parsePinDataType(repr, pinAndTypes[1])
+		return DirectedPinType{Sends, SignalPin{t}}, err
 	case "consume-signal":
-		t, _ := datatype.Parse(pinAndTypes[1])
-		return DirectedPinType{Receives, SignalPin{t}}, nil
-	case "send-command", "receive-command": // FIXME: correct parameter parsing
-		result, err := parseCommand(repr)
-		return result, err
+		t, err := parsePinDataType(repr, pinAndTypes[1])
+		return DirectedPinType{Receives, SignalPin{t}}, err
+	case "configuration":
+		t, err := parsePinDataType(repr, pinAndTypes[1])
+		return DirectedPinType{Sends, ConfigurationPin{t}}, err
+	case "send-command":
+		return parseCommand(Sends, repr, pinAndTypes[1])
+	case "receive-command":
+		return parseCommand(Receives, repr, pinAndTypes[1])
 	default:
 		return DirectedPinType{}, parsing.ManifestError{fmt.Sprintf("Unknown pin type: %s", pinAndTypes[0]), 0, 0}
 	}
 }
 
-func parseCommand(repr string) (DirectedPinType, error) {
-	tokenizer := datatype.NewTokenReader(repr)
-	ttype, token := tokenizer.Read()
-	if ttype != datatype.TOKEN_ALPHANUM {
-		return DirectedPinType{}, parsing.ManifestError{"Unexpected token: %s", 0, 0}
-	}
-	direction := Receives
-	if strings.HasPrefix(token, "send") {
-		direction = Sends
+// empty type stands for untyped pin
+func parsePinDataType(repr, body string) (datatype.DataType, error) {
+	if strings.TrimSpace(body) == "" {
+		return nil, nil
 	}
-	err := tokenizer.ReadAssertToken(datatype.TOKEN_OPEN_BRS)
+	t, err := datatype.Parse(body)
 	if err != nil {
-		return DirectedPinType{}, err
+		return nil, parsing.ManifestError{fmt.Sprintf("Malformed data type in %s: %s", repr, err), 0, 0}
 	}
-	args := [3]datatype.Record{}
-	for i := 0; i < 3; i++ {
-		stopTokens := []datatype.TokenType{datatype.TOKEN_CLOSING_BRS, datatype.TOKEN_COMMA, datatype.TOKEN_EOF}
-		if i == 2 {
-			stopTokens = []datatype.TokenType{datatype.TOKEN_CLOSING_BRS, datatype.TOKEN_EOF}
-		}
-		body, err := datatype.ParseRecordBodyFromTokens(&tokenizer, stopTokens)
+	return t, nil
+}
+
+// command body is arguments, optionally followed by "=> result"
+// or "=> progress => result"
+func parseCommand(direction Direction, repr, body string) (DirectedPinType, error) {
+	sections := strings.Split(body, "=>")
+	if len(sections) > 3 {
+		return DirectedPinType{}, parsing.ManifestError{fmt.Sprintf("Malformed command: %s", repr), 0, 0}
+	}
+	records := make([]datatype.Record, len(sections))
+	for i, section := range sections {
+		tokenizer := datatype.NewTokenReader(section)
+		fields, err := datatype.ParseRecordBodyFromTokens(&tokenizer, []datatype.TokenType{datatype.TOKEN_EOF})
 		if err != nil {
-			return DirectedPinType{}, err
+			return DirectedPinType{}, parsing.ManifestError{fmt.Sprintf("Malformed data type in %s: %s", repr, err), 0, 0}
 		}
-		args[i] = datatype.Record{body}
+		records[i] = datatype.Record{fields}
 	}
-	return DirectedPinType{direction, CommandPin{args[0], args[1], args[2]}}, nil
+	command := CommandPin{Arguments: records[0]}
+	switch len(records) {
+	case 2:
+		command.Result = records[1]
+	case 3:
+		command.Progress, command.Result = records[1], records[2]
+	}
+	return DirectedPinType{direction, command}, nil
 }
 
 func parseBindings(repr [][]string) ([]Binding, error) {
@@ -181,24 +264,13 @@ func parseBindings(repr [][]string) ([]Binding, error) {
 }
 
 func parseBinding(left, right string) (Binding, error) {
-	first, err := parseBindingTarget(left)
+	first, err := ParseBindingTarget(left)
 	if err != nil {
 		return Binding{}, err
 	}
-	second, err := parseBindingTarget(right)
+	second, err := ParseBindingTarget(right)
 	if err != nil {
 		return Binding{}, err
 	}
 	return Binding{first, second}, nil
 }
-
-func parseBindingTarget(target string) (BindingTarget, error) {
-	splitted := strings.Split(target, "#")
-	if len(splitted) == 1 {
-		return ComponentBindingTarget{ComponentId{strings.Split(splitted[0], ".")}}, nil
-	} else if len(splitted) == 2 {
-		return InterfaceBindingTarget{ComponentId{strings.Split(splitted[0], ".")}, splitted[1]}, nil
-	} else {
-		return nil, parsing.ManifestError{fmt.Sprintf("Unexpected binding target: %s", target), 0, 0}
-	}
-}
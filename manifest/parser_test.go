@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"github.com/chemikadze/gonomi/manifest/datatype"
+	"reflect"
 	"testing"
 )
 
@@ -182,3 +183,119 @@ func TestBindings(t *testing.T) {
 			{InterfaceBindingTarget{ComponentId{[]string{"x"}}, "i"}, ComponentBindingTarget{ComponentId{[]string{"y"}}}},
 		}}})
 }
+
+func TestPinTypeString(t *testing.T) {
+	cases := map[string]string{
+		"publish-signal(string)":                  "publish-signal(string)",
+		"consume-signal( list< list <string> > )": "consume-signal(list<list<string>>)",
+		"configuration(map<string,int>)":          "configuration(map<string, int>)",
+		"send-command()":                          "send-command()",
+		"receive-command(string x, int b)":        "receive-command(int b, string x)",
+		"send-command(string x => int r)":         "send-command(string x => int r)",
+		"send-command( => int p => list<int> r)":  "send-command(=> int p => list<int> r)",
+	}
+	for repr, expected := range cases {
+		pin, err := ParsePinType(repr)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if pin.String() != expected {
+			t.Errorf("\nFormatted: %v\nExpect:    %v", pin.String(), expected)
+		}
+	}
+	for _, repr := range []string{"publish-signal", "publish-signal(lst<string>)", "configuration(list<)", "send-command(string)", "send-command(=> => =>)"} {
+		if _, err := ParsePinType(repr); err == nil {
+			t.Errorf("Error expected for %s", repr)
+		}
+	}
+}
+
+func TestPinTypeRoundTrip(t *testing.T) {
+	pins := []DirectedPinType{
+		{Sends, SignalPin{datatype.Map{datatype.String{}, datatype.List{datatype.Int{}}}}},
+		{Receives, SignalPin{datatype.Record{map[string]datatype.DataType{"a": datatype.Bool{}}}}},
+		{Sends, ConfigurationPin{datatype.Int{}}},
+		{Sends, CommandPin{}},
+		{Receives, CommandPin{Arguments: datatype.Record{map[string]datatype.DataType{"q": datatype.String{}, "limit": datatype.Int{}}}}},
+		{Sends, CommandPin{Result: datatype.Record{map[string]datatype.DataType{"r": datatype.String{}}}}},
+		{Receives, CommandPin{
+			datatype.Record{map[string]datatype.DataType{"q": datatype.String{}}},
+			datatype.Record{map[string]datatype.DataType{"done": datatype.Int{}}},
+			datatype.Record{map[string]datatype.DataType{"rows": datatype.List{datatype.String{}}}},
+		}},
+		{Sends, CommandPin{Progress: datatype.Record{map[string]datatype.DataType{"done": datatype.Int{}}}}},
+	}
+	for _, pin := range pins {
+		parsed, err := ParsePinType(pin.String())
+		if err != nil {
+			t.Errorf("%s: %s", pin, err)
+			continue
+		}
+		if parsed.String() != pin.String() || !reflect.DeepEqual(normalizePin(parsed), normalizePin(pin)) {
+			t.Errorf("\nParsed: %v\nExpect: %v", parsed, pin)
+		}
+	}
+}
+
+// empty records are parsed with nil fields
+func normalizePin(pin DirectedPinType) DirectedPinType {
+	if command, ok := pin.PinType.(CommandPin); ok {
+		for _, record := range []*datatype.Record{&command.Arguments, &command.Progress, &command.Result} {
+			if len(record.Fields) == 0 {
+				record.Fields = nil
+			}
+		}
+		pin.PinType = command
+	}
+	return pin
+}
+
+func TestApplicationInterfaces(t *testing.T) {
+	testManifest(t, `
+        application:
+            interfaces:
+                sql:
+                    url: bind(db#sql.url)
+            components:
+                db:
+                    type: test.Database
+    `, Application{CompositeComponent{
+		Components: map[string]Component{
+			"db": LeafComponent{
+				Type:          Type{"test.Database"},
+				Configuration: Configuration{},
+				Interfaces:    map[string]LeafInterface{},
+			},
+		},
+		Interfaces: map[string]CompositeInterface{
+			"sql": {"url": PinBinding{"db", PinId{"sql", "url"}}},
+		},
+	}})
+}
+
+func TestParsePinBinding(t *testing.T) {
+	cases := map[string]PinBinding{
+		"bind(db#sql.url)":          {"db", PinId{"sql", "url"}},
+		" bind( a.b#sql.url ) ":     {"a.b", PinId{"sql", "url"}},
+		"bind(db#sql.schema.table)": {"db", PinId{"sql.schema", "table"}},
+	}
+	for repr, expected := range cases {
+		binding, err := ParsePinBinding(repr)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if binding != expected {
+			t.Errorf("\nParsed: %v\nExpect: %v", binding, expected)
+		}
+	}
+	for _, repr := range []string{"db#sql.url", "bind(db.sql.url)", "bind(#sql.url)", "bind(db#sql)", "bind(db#sql.)", "bind(db#.url)"} {
+		if _, err := ParsePinBinding(repr); err == nil {
+			t.Errorf("Error expected for %s", repr)
+		}
+	}
+	if s := (PinBinding{"a.b", PinId{"i", "p"}}).String(); s != "bind(a.b#i.p)" {
+		t.Errorf("Unexpected representation %s", s)
+	}
+}
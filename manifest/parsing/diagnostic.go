@@ -0,0 +1,46 @@
+package parsing
+
+import (
+	"fmt"
+	"strings"
+)
+
+type Severity int
+
+const (
+	Warning Severity = iota
+	Error
+)
+
+func (s Severity) String() string {
+	if s == Error {
+		return "error"
+	}
+	return "warning"
+}
+
+// problem found in manifest which did not necessarily stop parsing
+type Diagnostic struct {
+	Severity Severity
+	Message  string
+	Line     int
+	Column   int
+}
+
+func (d Diagnostic) String() string {
+	if d.Line == 0 {
+		return d.Severity.String() + ": " + d.Message
+	}
+	return fmt.Sprintf("%d:%d: %s: %s", d.Line, d.Column, d.Severity, d.Message)
+}
+
+// diagnostics reported as single error, one per line
+type Diagnostics []Diagnostic
+
+func (d Diagnostics) Error() string {
+	lines := make([]string, 0, len(d))
+	for _, diagnostic := range d {
+		lines = append(lines, diagnostic.String())
+	}
+	return strings.Join(lines, "\n")
+}
@@ -1,11 +1,19 @@
 package parsing
 
+import (
+	"fmt"
+)
+
 type ManifestError struct {
 	Message string
 	Line    int
 	Column  int
 }
 
+// message is prefixed with position when it is known
 func (e ManifestError) Error() string {
-	return e.Message
+	if e.Line == 0 {
+		return e.Message
+	}
+	return fmt.Sprintf("%d:%d: %s", e.Line, e.Column, e.Message)
 }
@@ -0,0 +1,28 @@
+package parsing
+
+import (
+	"fmt"
+)
+
+// error located in manifest file, unknown parts of position are zero
+type PositionError struct {
+	File    string
+	Line    int
+	Column  int
+	Message string
+}
+
+// file:line:col: message
+func (e PositionError) Error() string {
+	position := e.File
+	if e.Line != 0 {
+		position += fmt.Sprintf(":%d", e.Line)
+		if e.Column != 0 {
+			position += fmt.Sprintf(":%d", e.Column)
+		}
+	}
+	if position == "" {
+		return e.Message
+	}
+	return position + ": " + e.Message
+}
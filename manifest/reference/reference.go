@@ -0,0 +1,322 @@
+// Package reference models components standing for other applications:
+// reference.Submodule is an application launched as part of this one and
+// reference.Service is an already running shared instance:
+//
+//	db:
+//	    type: reference.Submodule
+//	    configuration:
+//	        __locator.application-id: database
+//	    interfaces:
+//	        sql:
+//	            url: publish-signal(string)
+//
+// given a registry of manifests, interfaces exported by referenced
+// applications are resolved and bindings to references are type-checked
+package reference
+
+import (
+	"errors"
+	"fmt"
+	"github.com/chemikadze/gonomi/manifest"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	SubmoduleTypeName     = "reference.Submodule"
+	ServiceTypeName       = "reference.Service"
+	ApplicationLocatorKey = "__locator.application-id"
+	InstanceLocatorKey    = "__locator.instance-id"
+)
+
+type Kind int
+
+const (
+	Submodule Kind = iota
+	Service
+)
+
+func (k Kind) String() string {
+	if k == Submodule {
+		return "submodule"
+	}
+	return "service"
+}
+
+// what reference points to, submodules are located by application,
+// services either by application or by running instance
+type Locator struct {
+	Application string
+	Instance    string
+}
+
+func (l Locator) String() string {
+	if l.Instance != "" {
+		return "instance " + l.Instance
+	}
+	return "application " + l.Application
+}
+
+type Reference struct {
+	Kind      Kind
+	Locator   Locator
+	Component manifest.LeafComponent
+}
+
+// gives typed view of reference component, false is returned for
+// components of other types
+func Parse(c manifest.LeafComponent) (Reference, bool, error) {
+	var kind Kind
+	switch c.Type.Name {
+	case SubmoduleTypeName:
+		kind = Submodule
+	case ServiceTypeName:
+		kind = Service
+	default:
+		return Reference{}, false, nil
+	}
+	locator := Locator{}
+	keys := []string{ApplicationLocatorKey, InstanceLocatorKey}
+	for i, target := range []*string{&locator.Application, &locator.Instance} {
+		key := keys[i]
+		value, ok := c.Configuration[key]
+		if !ok {
+			continue
+		}
+		s, ok := value.(string)
+		if !ok || s == "" {
+			return Reference{}, true, errors.New(fmt.Sprintf("Locator %s must be non-empty string", key))
+		}
+		*target = s
+	}
+	switch {
+	case kind == Submodule && locator.Application == "":
+		return Reference{}, true, errors.New(fmt.Sprintf("Submodule requires %s", ApplicationLocatorKey))
+	case kind == Submodule && locator.Instance != "":
+		return Reference{}, true, errors.New(fmt.Sprintf("Submodule can't be located by %s", InstanceLocatorKey))
+	case kind == Service && locator.Application == "" && locator.Instance == "":
+		return Reference{}, true, errors.New(fmt.Sprintf("Service requires %s or %s", ApplicationLocatorKey, InstanceLocatorKey))
+	}
+	return Reference{kind, locator, c}, true, nil
+}
+
+// manifests of known applications by application id and of running
+// instances by instance id, the two kinds of ids don't clash
+type Registry struct {
+	Applications map[string]manifest.Application
+	Instances    map[string]manifest.Application
+}
+
+// loads every manifest in directory as application and every manifest
+// in its instances subdirectory as instance, id is file name without
+// extension: database.yml is application database, instances/db-1.yml
+// is instance db-1
+func LoadRegistry(dir string) (Registry, error) {
+	applications, err := loadManifests(dir)
+	if err != nil {
+		return Registry{}, err
+	}
+	instances := map[string]manifest.Application{}
+	if info, err := os.Stat(filepath.Join(dir, "instances")); err == nil && info.IsDir() {
+		if instances, err = loadManifests(filepath.Join(dir, "instances")); err != nil {
+			return Registry{}, err
+		}
+	}
+	return Registry{applications, instances}, nil
+}
+
+// applications of manifests in directory by file name without extension
+func loadManifests(dir string) (map[string]manifest.Application, error) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	result := map[string]manifest.Application{}
+	for _, file := range files {
+		ext := filepath.Ext(file.Name())
+		if file.IsDir() || (ext != ".yml" && ext != ".yaml" && ext != ".json") {
+			continue
+		}
+		name := strings.TrimSuffix(file.Name(), ext)
+		if _, ok := result[name]; ok {
+			return nil, errors.New(fmt.Sprintf("%s: duplicate manifest of %s", filepath.Join(dir, file.Name()), name))
+		}
+		loaded, err := manifest.LoadFile(filepath.Join(dir, file.Name()))
+		if err != nil {
+			return nil, err
+		}
+		if len(loaded) != 1 {
+			return nil, errors.New(fmt.Sprintf("%s: expected one application, found %d", file.Name(), len(loaded)))
+		}
+		result[name] = loaded[0].Application
+	}
+	return result, nil
+}
+
+// application reference points to, instance is preferred over application
+func (r Registry) Lookup(locator Locator) (manifest.Application, bool) {
+	if locator.Instance != "" {
+		if app, ok := r.Instances[locator.Instance]; ok {
+			return app, true
+		}
+	}
+	if locator.Application != "" {
+		app, ok := r.Applications[locator.Application]
+		return app, ok
+	}
+	return manifest.Application{}, false
+}
+
+// interfaces application exports with pins resolved through re-exports
+func Exports(app manifest.Application) (map[string]manifest.LeafInterface, error) {
+	result := make(map[string]manifest.LeafInterface)
+	for name := range app.Interfaces {
+		iface, err := app.LookupInterface(manifest.ComponentId{}, name)
+		if err != nil {
+			return nil, err
+		}
+		result[name] = iface
+	}
+	return result, nil
+}
+
+// replaces interfaces of references with ones exported by referenced
+// applications; references without interfaces get all exported ones, declared
+// pins have to be exported with the same type
+func Resolve(app manifest.Application, registry Registry) (manifest.Application, []error) {
+	problems := []error{}
+	resolved := resolveComposite(manifest.ComponentId{}, app.CompositeComponent, registry, &problems)
+	return manifest.Application{resolved}, problems
+}
+
+func resolveComposite(id manifest.ComponentId, c manifest.CompositeComponent, registry Registry, problems *[]error) manifest.CompositeComponent {
+	if c.Components == nil {
+		return c
+	}
+	components := make(map[string]manifest.Component, len(c.Components))
+	for _, name := range componentNames(c.Components) {
+		child := id.Child(name)
+		switch component := c.Components[name].(type) {
+		case manifest.CompositeComponent:
+			components[name] = resolveComposite(child, component, registry, problems)
+		case manifest.LeafComponent:
+			components[name] = resolveLeaf(child, component, registry, problems)
+		default:
+			components[name] = component
+		}
+	}
+	c.Components = components
+	return c
+}
+
+func resolveLeaf(id manifest.ComponentId, c manifest.LeafComponent, registry Registry, problems *[]error) manifest.LeafComponent {
+	fail := func(format string, args ...interface{}) manifest.LeafComponent {
+		*problems = append(*problems, errors.New(fmt.Sprintf("Component %s: ", id)+fmt.Sprintf(format, args...)))
+		return c
+	}
+	ref, ok, err := Parse(c)
+	if !ok {
+		return c
+	}
+	if err != nil {
+		return fail("%s", err)
+	}
+	app, ok := registry.Lookup(ref.Locator)
+	if !ok {
+		return fail("unknown %s", ref.Locator)
+	}
+	exported, err := Exports(app)
+	if err != nil {
+		return fail("%s: %s", ref.Locator, err)
+	}
+	if len(c.Interfaces) == 0 {
+		c.Interfaces = exported
+		return c
+	}
+	for _, name := range interfaceNames(c.Interfaces) {
+		iface, ok := exported[name]
+		if !ok {
+			fail("interface %s is not exported by %s", name, ref.Locator)
+			continue
+		}
+		declared := c.Interfaces[name]
+		for _, pin := range pinNames(declared.Pins) {
+			exportedPin, ok := iface.Pins[pin]
+			if !ok {
+				fail("pin %s.%s is not exported by %s", name, pin, ref.Locator)
+			} else if declared.Pins[pin].String() != exportedPin.String() {
+				fail("pin %s.%s is declared as %s, but %s exports %s", name, pin, declared.Pins[pin], ref.Locator, exportedPin)
+			}
+		}
+	}
+	return c
+}
+
+// resolves references and checks that pins connected by bindings
+// carry the same data types
+func Check(app manifest.Application, registry Registry) []error {
+	resolved, problems := Resolve(app, registry)
+	if len(problems) != 0 {
+		return problems
+	}
+	graph, err := manifest.Flatten(resolved)
+	if err != nil {
+		return []error{err}
+	}
+	for _, connection := range graph.Connections {
+		from, err := resolved.LookupPin(connection.From.Component, connection.From.Pin)
+		if err != nil {
+			problems = append(problems, err)
+			continue
+		}
+		to, err := resolved.LookupPin(connection.To.Component, connection.To.Pin)
+		if err != nil {
+			problems = append(problems, err)
+			continue
+		}
+		if fromType, toType := payload(from), payload(to); fromType != "" && toType != "" && fromType != toType {
+			problems = append(problems, errors.New(fmt.Sprintf("Binding %s -> %s: %s doesn't match %s", connection.From, connection.To, fromType, toType)))
+		}
+	}
+	return problems
+}
+
+// data carried by pin regardless of its direction, empty for untyped pins
+func payload(p manifest.DirectedPinType) string {
+	s := p.String()
+	start := strings.Index(s, "(")
+	if start < 0 || !strings.HasSuffix(s, ")") {
+		return ""
+	}
+	return s[start+1 : len(s)-1]
+}
+
+func componentNames(components map[string]manifest.Component) []string {
+	names := make([]string, 0, len(components))
+	for name := range components {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func interfaceNames(interfaces map[string]manifest.LeafInterface) []string {
+	names := make([]string, 0, len(interfaces))
+	for name := range interfaces {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func pinNames(pins map[string]manifest.DirectedPinType) []string {
+	names := make([]string, 0, len(pins))
+	for name := range pins {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,223 @@
+package reference
+
+import (
+	"github.com/chemikadze/gonomi/manifest"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const database = `
+application:
+    interfaces:
+        sql:
+            url: bind(db#sql.url)
+            query: bind(db#sql.query)
+    components:
+        db:
+            type: test.Database
+            interfaces:
+                sql:
+                    url: publish-signal(string)
+                    query: receive-command(string q)
+`
+
+func parse(t *testing.T, src string) manifest.Application {
+	app, err := manifest.Parse(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return app
+}
+
+func registry(t *testing.T) Registry {
+	dir, err := ioutil.TempDir("", "gonomi")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	files := map[string]string{
+		"database.yml": database,
+		"README":       "not a manifest",
+	}
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	r, err := LoadRegistry(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func TestParse(t *testing.T) {
+	cases := []struct {
+		typeName      string
+		configuration manifest.Configuration
+		locator       Locator
+		err           string
+	}{
+		{SubmoduleTypeName, manifest.Configuration{ApplicationLocatorKey: "database"}, Locator{"database", ""}, ""},
+		{ServiceTypeName, manifest.Configuration{InstanceLocatorKey: "5a1b"}, Locator{"", "5a1b"}, ""},
+		{SubmoduleTypeName, manifest.Configuration{}, Locator{}, "Submodule requires __locator.application-id"},
+		{SubmoduleTypeName, manifest.Configuration{ApplicationLocatorKey: "a", InstanceLocatorKey: "b"}, Locator{}, "Submodule can't be located by __locator.instance-id"},
+		{ServiceTypeName, manifest.Configuration{}, Locator{}, "Service requires __locator.application-id or __locator.instance-id"},
+		{ServiceTypeName, manifest.Configuration{InstanceLocatorKey: 1}, Locator{}, "Locator __locator.instance-id must be non-empty string"},
+	}
+	for _, c := range cases {
+		ref, ok, err := Parse(manifest.LeafComponent{manifest.Type{c.typeName}, c.configuration, nil})
+		if !ok {
+			t.Errorf("%s should be recognized as reference", c.typeName)
+		}
+		if c.err != "" {
+			if err == nil || err.Error() != c.err {
+				t.Errorf("\nError:  %v\nExpect: %s", err, c.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Error(err)
+		} else if ref.Locator != c.locator {
+			t.Errorf("\nLocator: %v\nExpect:  %v", ref.Locator, c.locator)
+		}
+	}
+	if _, ok, _ := Parse(manifest.LeafComponent{manifest.Type{"test.Component"}, nil, nil}); ok {
+		t.Error("Only reference types should be recognized")
+	}
+}
+
+func TestResolveExportedInterfaces(t *testing.T) {
+	app := parse(t, `
+application:
+    components:
+        db:
+            type: reference.Submodule
+            configuration:
+                __locator.application-id: database
+`)
+	resolved, problems := Resolve(app, registry(t))
+	if len(problems) != 0 {
+		t.Fatal(problems)
+	}
+	iface, err := resolved.LookupInterface(manifest.ComponentId{[]string{"db"}}, "sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pins := map[string]string{}
+	for name, pin := range iface.Pins {
+		pins[name] = pin.String()
+	}
+	expected := map[string]string{
+		"url":   "publish-signal(string)",
+		"query": "receive-command(string q)",
+	}
+	if !reflect.DeepEqual(pins, expected) {
+		t.Errorf("\nPins:   %v\nExpect: %v", pins, expected)
+	}
+	if len(app.Components["db"].(manifest.LeafComponent).Interfaces) != 0 {
+		t.Error("Original application should not be modified")
+	}
+}
+
+func TestResolveDeclaredInterfaces(t *testing.T) {
+	app := parse(t, `
+application:
+    components:
+        db:
+            type: reference.Submodule
+            configuration:
+                __locator.application-id: database
+            interfaces:
+                sql:
+                    url: publish-signal(int)
+                    schema: publish-signal(string)
+                admin:
+                    reset: receive-command()
+        cache:
+            type: reference.Service
+            configuration:
+                __locator.instance-id: cache-1
+`)
+	_, problems := Resolve(app, registry(t))
+	expected := []string{
+		"Component cache: unknown instance cache-1",
+		"Component db: interface admin is not exported by application database",
+		"Component db: pin sql.schema is not exported by application database",
+		"Component db: pin sql.url is declared as publish-signal(int), but application database exports publish-signal(string)",
+	}
+	messages := []string{}
+	for _, problem := range problems {
+		messages = append(messages, problem.Error())
+	}
+	if !reflect.DeepEqual(messages, expected) {
+		t.Errorf("\nProblems: %q\nExpect:   %q", messages, expected)
+	}
+}
+
+func TestCheckBindings(t *testing.T) {
+	app := parse(t, `
+application:
+    components:
+        db:
+            type: reference.Submodule
+            configuration:
+                __locator.application-id: database
+        web:
+            type: test.Web
+            interfaces:
+                sql:
+                    url: consume-signal(int)
+                    query: send-command(string q)
+    bindings:
+        - [web, db]
+`)
+	problems := Check(app, registry(t))
+	if len(problems) != 1 || problems[0].Error() != "Binding db#sql.url -> web#sql.url: string doesn't match int" {
+		t.Errorf("Unexpected problems: %v", problems)
+	}
+}
+
+// instance and application sharing file name are kept apart
+func TestLoadRegistryInstances(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gonomi")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := os.Mkdir(filepath.Join(dir, "instances"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	instance := "application:\n    components:\n        cache:\n            type: test.Cache\n"
+	files := map[string]string{
+		"database.yml":           database,
+		"instances/database.yml": instance,
+	}
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	r, err := LoadRegistry(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if app, ok := r.Lookup(Locator{Application: "database"}); !ok || !app.Equal(parse(t, database)) {
+		t.Errorf("Unexpected application: %v", app)
+	}
+	if app, ok := r.Lookup(Locator{Instance: "database"}); !ok || !app.Equal(parse(t, instance)) {
+		t.Errorf("Unexpected instance: %v", app)
+	}
+	if _, ok := r.Lookup(Locator{Instance: "cache"}); ok {
+		t.Error("Unknown instance should not be found")
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "database.yaml"), []byte(database), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadRegistry(dir); err == nil {
+		t.Error("Duplicate application should fail")
+	}
+}
@@ -0,0 +1,170 @@
+package manifest
+
+import (
+	"fmt"
+	"gopkg.in/yaml.v2"
+	"sort"
+)
+
+// whole manifest document: application and sections describing
+// environment it runs in
+//
+//	application: ...
+//	services:
+//	    keystore:
+//	        type: environment.KeyStore
+//	        bindings: [db, web#keys]
+//	policies:
+//	    scaling:
+//	        type: environment.AutoScaling
+//	        configuration: {max: 5}
+//	        components: [web]
+type Manifest struct {
+	Application Application
+	Imports     []string
+	Services    map[string]Service
+	Policies    map[string]Policy
+	// top-level sections parser doesn't model, as decoded from YAML
+	Sections map[string]interface{}
+}
+
+// service provided by environment, e.g. key store or cloud account,
+// bindings list components and interfaces using it
+type Service struct {
+	Type          Type
+	Configuration Configuration
+	Bindings      []BindingTarget
+}
+
+// rule environment applies to components, e.g. scaling or access policy
+type Policy struct {
+	Type          Type
+	Configuration Configuration
+	Components    []ComponentId
+}
+
+type manifestRoot struct {
+	Application application               `yaml:"application"`
+	Imports     []string                  `yaml:"imports"`
+	Services    map[string]serviceSection `yaml:"services"`
+	Policies    map[string]policySection  `yaml:"policies"`
+	Sections    map[string]interface{}    `yaml:",inline"`
+}
+
+type serviceSection struct {
+	Type          string                      `yaml:"type"`
+	Configuration map[interface{}]interface{} `yaml:"configuration"`
+	Bindings      []string                    `yaml:"bindings"`
+}
+
+type policySection struct {
+	Type          string                      `yaml:"type"`
+	Configuration map[interface{}]interface{} `yaml:"configuration"`
+	Components    []string                    `yaml:"components"`
+}
+
+// problem of service or policy, Index is position of binding target
+// or component in its list
+type SectionError struct {
+	// "services" or "policies"
+	Section string
+	Name    string
+	Index   int
+	Err     error
+}
+
+func (e SectionError) Error() string {
+	if e.Section == "policies" {
+		return fmt.Sprintf("Policy %s: %s", e.Name, e.Err)
+	}
+	return fmt.Sprintf("Service %s: %s", e.Name, e.Err)
+}
+
+// parses manifest with all of its sections, application is parsed as by Parse
+func ParseManifest(manifest string) (Manifest, error) {
+	root := manifestRoot{}
+	if err := yaml.Unmarshal([]byte(manifest), &root); err != nil {
+		return Manifest{}, err
+	}
+	app, err := parseApplication(root.Application)
+	if err != nil {
+		return Manifest{}, err
+	}
+	m := Manifest{Application: app, Imports: root.Imports}
+	if len(root.Services) != 0 {
+		m.Services = make(map[string]Service)
+	}
+	for name, s := range root.Services {
+		bindings := []BindingTarget{}
+		for i, repr := range s.Bindings {
+			target, err := ParseBindingTarget(repr)
+			if err != nil {
+				return Manifest{}, SectionError{"services", name, i, err}
+			}
+			bindings = append(bindings, target)
+		}
+		m.Services[name] = Service{Type{s.Type}, Configuration(yamlMapToSimpleMap(s.Configuration)), bindings}
+	}
+	if len(root.Policies) != 0 {
+		m.Policies = make(map[string]Policy)
+	}
+	for name, p := range root.Policies {
+		components := []ComponentId{}
+		for i, repr := range p.Components {
+			id, err := ParseComponentId(repr)
+			if err != nil {
+				return Manifest{}, SectionError{"policies", name, i, err}
+			}
+			components = append(components, id)
+		}
+		m.Policies[name] = Policy{Type{p.Type}, Configuration(yamlMapToSimpleMap(p.Configuration)), components}
+	}
+	if len(root.Sections) != 0 {
+		m.Sections = root.Sections
+	}
+	return m, nil
+}
+
+// checks that services and policies refer to existing components
+// and interfaces of application
+func (m Manifest) Validate() []error {
+	problems := []error{}
+	for _, name := range sectionNames(m.Services) {
+		for i, target := range m.Services[name].Bindings {
+			id := BindingTargetComponent(target)
+			var err error
+			if target, ok := target.(InterfaceBindingTarget); ok {
+				_, err = m.Application.LookupInterface(id, target.Interface)
+			} else {
+				_, err = m.Application.Lookup(id)
+			}
+			if err != nil {
+				problems = append(problems, SectionError{"services", name, i, err})
+			}
+		}
+	}
+	for _, name := range sectionNames(m.Policies) {
+		for i, id := range m.Policies[name].Components {
+			if _, err := m.Application.Lookup(id); err != nil {
+				problems = append(problems, SectionError{"policies", name, i, err})
+			}
+		}
+	}
+	return problems
+}
+
+func sectionNames(m interface{}) []string {
+	names := []string{}
+	switch m := m.(type) {
+	case map[string]Service:
+		for name := range m {
+			names = append(names, name)
+		}
+	case map[string]Policy:
+		for name := range m {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
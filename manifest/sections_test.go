@@ -0,0 +1,122 @@
+package manifest
+
+import (
+	"github.com/chemikadze/gonomi/manifest/parsing"
+	"reflect"
+	"testing"
+)
+
+const environmentManifest = `
+imports: [lib/]
+application:
+    components:
+        db:
+            type: test.Database
+            interfaces:
+                keys:
+                    key: consume-signal(string)
+        web:
+            type: test.Web
+services:
+    keystore:
+        type: environment.KeyStore
+        configuration: {vault: main}
+        bindings: [db#keys, web, cache, web#keys]
+policies:
+    scaling:
+        type: environment.AutoScaling
+        configuration: {max: 5}
+        components: [web, web.app]
+    backup:
+        type: environment.Backup
+monitoring:
+    dashboards: [db]
+`
+
+func TestParseManifest(t *testing.T) {
+	m, err := ParseManifest(environmentManifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	app, _ := Parse(environmentManifest)
+	if !m.Application.Equal(app) {
+		t.Errorf("\nApplication: %v\nExpect:      %v", m.Application, app)
+	}
+	if !reflect.DeepEqual(m.Imports, []string{"lib/"}) {
+		t.Errorf("Unexpected imports: %v", m.Imports)
+	}
+	expectedServices := map[string]Service{
+		"keystore": {Type{"environment.KeyStore"}, Configuration{"vault": "main"}, []BindingTarget{
+			InterfaceBindingTarget{ComponentId{[]string{"db"}}, "keys"},
+			ComponentBindingTarget{ComponentId{[]string{"web"}}},
+			ComponentBindingTarget{ComponentId{[]string{"cache"}}},
+			InterfaceBindingTarget{ComponentId{[]string{"web"}}, "keys"},
+		}},
+	}
+	if !reflect.DeepEqual(m.Services, expectedServices) {
+		t.Errorf("\nServices: %v\nExpect:   %v", m.Services, expectedServices)
+	}
+	expectedPolicies := map[string]Policy{
+		"scaling": {Type{"environment.AutoScaling"}, Configuration{"max": 5}, []ComponentId{{[]string{"web"}}, {[]string{"web", "app"}}}},
+		"backup":  {Type{"environment.Backup"}, Configuration{}, []ComponentId{}},
+	}
+	if !reflect.DeepEqual(m.Policies, expectedPolicies) {
+		t.Errorf("\nPolicies: %v\nExpect:   %v", m.Policies, expectedPolicies)
+	}
+	expectedSections := map[string]interface{}{"monitoring": map[interface{}]interface{}{"dashboards": []interface{}{"db"}}}
+	if !reflect.DeepEqual(m.Sections, expectedSections) {
+		t.Errorf("\nSections: %v\nExpect:   %v", m.Sections, expectedSections)
+	}
+}
+
+func TestParseManifestError(t *testing.T) {
+	_, err := ParseManifest(`
+services:
+    keystore:
+        bindings: ["db#"]
+`)
+	if err == nil || err.Error() != "Service keystore: Unexpected binding target: db#" {
+		t.Error("Unexpected error:", err)
+	}
+}
+
+func TestValidateManifest(t *testing.T) {
+	m, err := ParseManifest(environmentManifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		"Service keystore: Component cache: no component cache in application",
+		"Service keystore: Component web: no interface keys",
+		"Policy scaling: Component web.app: web is not a composite",
+	}
+	problems := []string{}
+	for _, problem := range m.Validate() {
+		problems = append(problems, problem.Error())
+	}
+	if !reflect.DeepEqual(problems, expected) {
+		t.Errorf("\nProblems: %q\nExpect:   %q", problems, expected)
+	}
+}
+
+func TestSectionDiagnostics(t *testing.T) {
+	_, diagnostics, err := ParseWithDiagnostics(`
+services:
+    keystore:
+        type: environment.KeyStore
+        components: [db]
+policies:
+    backup:
+        bindings: [db]
+`, ParseOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []parsing.Diagnostic{
+		{parsing.Warning, "Service keystore: unknown key components", 5, 9},
+		{parsing.Warning, "Policy backup: unknown key bindings", 8, 9},
+	}
+	if !reflect.DeepEqual(diagnostics, expected) {
+		t.Errorf("\nReported: %v\nExpect:   %v", diagnostics, expected)
+	}
+}
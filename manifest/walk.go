@@ -0,0 +1,196 @@
+package manifest
+
+import (
+	"sort"
+)
+
+// tells Walk how to proceed after callback
+type WalkAction int
+
+const (
+	Continue WalkAction = iota
+	// don't descend into children of current node, ignored by leave callbacks
+	SkipChildren
+	// abort the whole walk
+	Stop
+)
+
+// callbacks of Walk, component ids are relative to application root,
+// which is visited as composite component with empty path
+type Visitor interface {
+	// pre-order visit of component, called before its children
+	EnterComponent(id ComponentId, c Component) WalkAction
+	// post-order visit of component, called after its children
+	LeaveComponent(id ComponentId, c Component) WalkAction
+	ConfigurationEntry(id ComponentId, key string, value interface{}) WalkAction
+	// interface of leaf component, children are its pins
+	Interface(id ComponentId, name string, iface LeafInterface) WalkAction
+	// interface of composite component re-exporting pins of children
+	CompositeInterface(id ComponentId, name string, iface CompositeInterface) WalkAction
+	Pin(id ComponentId, pin PinId, pinType DirectedPinType) WalkAction
+	// binding declared by composite component id
+	Binding(id ComponentId, binding Binding) WalkAction
+}
+
+// Visitor built from optional functions, missing ones continue the walk
+type VisitorFuncs struct {
+	OnEnterComponent     func(id ComponentId, c Component) WalkAction
+	OnLeaveComponent     func(id ComponentId, c Component) WalkAction
+	OnConfigurationEntry func(id ComponentId, key string, value interface{}) WalkAction
+	OnInterface          func(id ComponentId, name string, iface LeafInterface) WalkAction
+	OnCompositeInterface func(id ComponentId, name string, iface CompositeInterface) WalkAction
+	OnPin                func(id ComponentId, pin PinId, pinType DirectedPinType) WalkAction
+	OnBinding            func(id ComponentId, binding Binding) WalkAction
+}
+
+func (v VisitorFuncs) EnterComponent(id ComponentId, c Component) WalkAction {
+	if v.OnEnterComponent == nil {
+		return Continue
+	}
+	return v.OnEnterComponent(id, c)
+}
+
+func (v VisitorFuncs) LeaveComponent(id ComponentId, c Component) WalkAction {
+	if v.OnLeaveComponent == nil {
+		return Continue
+	}
+	return v.OnLeaveComponent(id, c)
+}
+
+func (v VisitorFuncs) ConfigurationEntry(id ComponentId, key string, value interface{}) WalkAction {
+	if v.OnConfigurationEntry == nil {
+		return Continue
+	}
+	return v.OnConfigurationEntry(id, key, value)
+}
+
+func (v VisitorFuncs) Interface(id ComponentId, name string, iface LeafInterface) WalkAction {
+	if v.OnInterface == nil {
+		return Continue
+	}
+	return v.OnInterface(id, name, iface)
+}
+
+func (v VisitorFuncs) CompositeInterface(id ComponentId, name string, iface CompositeInterface) WalkAction {
+	if v.OnCompositeInterface == nil {
+		return Continue
+	}
+	return v.OnCompositeInterface(id, name, iface)
+}
+
+func (v VisitorFuncs) Pin(id ComponentId, pin PinId, pinType DirectedPinType) WalkAction {
+	if v.OnPin == nil {
+		return Continue
+	}
+	return v.OnPin(id, pin, pinType)
+}
+
+func (v VisitorFuncs) Binding(id ComponentId, binding Binding) WalkAction {
+	if v.OnBinding == nil {
+		return Continue
+	}
+	return v.OnBinding(id, binding)
+}
+
+// visits every node of application in stable order: configuration, interfaces
+// with pins, child components and bindings, names sorted alphabetically;
+// returns false if walk was stopped by visitor
+func Walk(app Application, v Visitor) bool {
+	return walkComponent(nil, app.CompositeComponent, v) != Stop
+}
+
+func walkComponent(path []string, c Component, v Visitor) WalkAction {
+	id := ComponentId{path}
+	switch action := v.EnterComponent(id, c); action {
+	case Stop:
+		return Stop
+	case SkipChildren:
+		return Continue
+	}
+	for _, key := range sortedKeys(c.GetConfiguration()) {
+		if v.ConfigurationEntry(id, key, c.GetConfiguration()[key]) == Stop {
+			return Stop
+		}
+	}
+	switch c := c.(type) {
+	case LeafComponent:
+		names := make([]string, 0, len(c.Interfaces))
+		for name := range c.Interfaces {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if walkInterface(id, name, c.Interfaces[name], v) == Stop {
+				return Stop
+			}
+		}
+	case CompositeComponent:
+		if walkComposite(path, c, v) == Stop {
+			return Stop
+		}
+	}
+	if v.LeaveComponent(id, c) == Stop {
+		return Stop
+	}
+	return Continue
+}
+
+func walkInterface(id ComponentId, name string, iface LeafInterface, v Visitor) WalkAction {
+	switch action := v.Interface(id, name, iface); action {
+	case Stop:
+		return Stop
+	case SkipChildren:
+		return Continue
+	}
+	pins := make([]string, 0, len(iface.Pins))
+	for pin := range iface.Pins {
+		pins = append(pins, pin)
+	}
+	sort.Strings(pins)
+	for _, pin := range pins {
+		if v.Pin(id, PinId{name, pin}, iface.Pins[pin]) == Stop {
+			return Stop
+		}
+	}
+	return Continue
+}
+
+func walkComposite(path []string, c CompositeComponent, v Visitor) WalkAction {
+	id := ComponentId{path}
+	names := make([]string, 0, len(c.Interfaces))
+	for name := range c.Interfaces {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if v.CompositeInterface(id, name, c.Interfaces[name]) == Stop {
+			return Stop
+		}
+	}
+	names = make([]string, 0, len(c.Components))
+	for name := range c.Components {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		childPath := append(append([]string{}, path...), name)
+		if walkComponent(childPath, c.Components[name], v) == Stop {
+			return Stop
+		}
+	}
+	for _, binding := range c.Bindings {
+		if v.Binding(id, binding) == Stop {
+			return Stop
+		}
+	}
+	return Continue
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,108 @@
+package manifest
+
+import (
+	"github.com/chemikadze/gonomi/manifest/datatype"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var walkApp = Application{CompositeComponent{
+	Components: map[string]Component{
+		"b": CompositeComponent{
+			Type: Type{CompositeTypeName},
+			Components: map[string]Component{
+				"c": LeafComponent{Type: Type{"test.Component"}},
+			},
+			Interfaces: map[string]CompositeInterface{
+				"e": CompositeInterface{"p": PinBinding{"c", PinId{"i", "p"}}},
+			},
+		},
+		"a": LeafComponent{
+			Type:          Type{"test.Component"},
+			Configuration: Configuration{"k": "v"},
+			Interfaces: map[string]LeafInterface{
+				"i": LeafInterface{Pins: map[string]DirectedPinType{
+					"q": {Receives, SignalPin{datatype.Int{}}},
+					"p": {Sends, SignalPin{datatype.String{}}},
+				}},
+			},
+		},
+	},
+	Bindings: []Binding{
+		{ComponentBindingTarget{ComponentId{[]string{"a"}}}, ComponentBindingTarget{ComponentId{[]string{"b"}}}},
+	},
+}}
+
+func recordingVisitor(visits *[]string, actions map[string]WalkAction) VisitorFuncs {
+	record := func(visit string) WalkAction {
+		*visits = append(*visits, visit)
+		return actions[visit]
+	}
+	return VisitorFuncs{
+		OnEnterComponent: func(id ComponentId, c Component) WalkAction {
+			return record("enter " + strings.Join(id.Path, "."))
+		},
+		OnLeaveComponent: func(id ComponentId, c Component) WalkAction {
+			return record("leave " + strings.Join(id.Path, "."))
+		},
+		OnConfigurationEntry: func(id ComponentId, key string, value interface{}) WalkAction {
+			return record("config " + key)
+		},
+		OnInterface: func(id ComponentId, name string, iface LeafInterface) WalkAction {
+			return record("interface " + name)
+		},
+		OnCompositeInterface: func(id ComponentId, name string, iface CompositeInterface) WalkAction {
+			return record("composite interface " + name)
+		},
+		OnPin: func(id ComponentId, pin PinId, pinType DirectedPinType) WalkAction {
+			return record("pin " + pin.Pin)
+		},
+		OnBinding: func(id ComponentId, binding Binding) WalkAction {
+			return record("binding")
+		},
+	}
+}
+
+func TestWalk(t *testing.T) {
+	visits := []string{}
+	if !Walk(walkApp, recordingVisitor(&visits, nil)) {
+		t.Error("Walk should complete")
+	}
+	expected := []string{
+		"enter ",
+		"enter a", "config k", "interface i", "pin p", "pin q", "leave a",
+		"enter b", "composite interface e", "enter b.c", "leave b.c", "leave b",
+		"binding",
+		"leave ",
+	}
+	if !reflect.DeepEqual(visits, expected) {
+		t.Errorf("\nVisited: %q\nExpect:  %q", visits, expected)
+	}
+}
+
+func TestWalkSkipChildren(t *testing.T) {
+	visits := []string{}
+	Walk(walkApp, recordingVisitor(&visits, map[string]WalkAction{"interface i": SkipChildren, "enter b": SkipChildren}))
+	expected := []string{
+		"enter ",
+		"enter a", "config k", "interface i", "leave a",
+		"enter b",
+		"binding",
+		"leave ",
+	}
+	if !reflect.DeepEqual(visits, expected) {
+		t.Errorf("\nVisited: %q\nExpect:  %q", visits, expected)
+	}
+}
+
+func TestWalkStop(t *testing.T) {
+	visits := []string{}
+	if Walk(walkApp, recordingVisitor(&visits, map[string]WalkAction{"pin p": Stop})) {
+		t.Error("Walk should be stopped")
+	}
+	expected := []string{"enter ", "enter a", "config k", "interface i", "pin p"}
+	if !reflect.DeepEqual(visits, expected) {
+		t.Errorf("\nVisited: %q\nExpect:  %q", visits, expected)
+	}
+}
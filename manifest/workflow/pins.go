@@ -0,0 +1,145 @@
+package workflow
+
+import (
+	"fmt"
+	"github.com/chemikadze/gonomi/manifest"
+	"github.com/chemikadze/gonomi/manifest/datatype"
+	"github.com/chemikadze/gonomi/manifest/expression"
+	"github.com/chemikadze/gonomi/manifest/parsing"
+	"sort"
+	"strings"
+)
+
+// pin of leaf component with its full name
+type namedPin struct {
+	name string
+	pin  manifest.DirectedPinType
+}
+
+// links workflows to interface of component: every publish-signal pin has to be
+// returned by some workflow with value matching its data type, returns have to
+// target existing publish-signal pins, parameters without defaults have to come
+// from configuration pins, defaults have to match their data types and every
+// configuration pin has to be a parameter;
+// returns and parameters are matched by pin name or by interface.pin
+func CheckPins(c manifest.LeafComponent, workflows map[string]Workflow) []error {
+	published := pinsOf(c, func(p manifest.DirectedPinType) bool {
+		_, ok := p.PinType.(manifest.SignalPin)
+		return ok && p.Direction.IsSend()
+	})
+	configured := pinsOf(c, func(p manifest.DirectedPinType) bool {
+		_, ok := p.PinType.(manifest.ConfigurationPin)
+		return ok
+	})
+	problems := []error{}
+	returned := map[string]bool{}
+	parameters := map[string]bool{}
+	names := make([]string, 0, len(workflows))
+	for name := range workflows {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		w := workflows[name]
+		for _, key := range returnKeys(w.Return) {
+			matches := matchPin(published, key)
+			if problem := matchProblem(matches, "publish-signal", key); problem != "" {
+				problems = append(problems, workflowError(name, "return %s: %s", key, problem))
+				continue
+			}
+			pin := matches[0]
+			returned[pin.name] = true
+			value := w.Return[key].Value
+			if err := datatype.CheckSkipping(pin.pin.PinType.(manifest.SignalPin).DataType, value, isExpression); err != nil {
+				problems = append(problems, workflowError(name, "return %s: value doesn't match pin %s: %s", key, pin.name, err))
+			}
+		}
+		for _, parameter := range w.Parameters {
+			matches := matchPin(configured, parameter.Name)
+			if problem := matchProblem(matches, "configuration", parameter.Name); problem != "" {
+				if parameter.Default == nil || len(matches) > 1 {
+					problems = append(problems, workflowError(name, "parameter %s: %s", parameter.Name, problem))
+				}
+				continue
+			}
+			pin := matches[0]
+			parameters[pin.name] = true
+			if parameter.Default == nil {
+				continue
+			}
+			if err := datatype.CheckSkipping(pin.pin.PinType.(manifest.ConfigurationPin).DataType, parameter.Default, isExpression); err != nil {
+				problems = append(problems, workflowError(name, "parameter %s: default doesn't match pin %s: %s", parameter.Name, pin.name, err))
+			}
+		}
+	}
+	for _, pin := range published {
+		if !returned[pin.name] {
+			problems = append(problems, parsing.ManifestError{fmt.Sprintf("Pin %s is never returned by workflows", pin.name), 0, 0})
+		}
+	}
+	for _, pin := range configured {
+		if !parameters[pin.name] {
+			problems = append(problems, parsing.ManifestError{fmt.Sprintf("Configuration pin %s is not a parameter of any workflow", pin.name), 0, 0})
+		}
+	}
+	return problems
+}
+
+// pins sorted by full name
+func pinsOf(c manifest.LeafComponent, filter func(manifest.DirectedPinType) bool) []namedPin {
+	pins := []namedPin{}
+	for iface, i := range c.Interfaces {
+		for name, pin := range i.Pins {
+			if filter(pin) {
+				pins = append(pins, namedPin{iface + "." + name, pin})
+			}
+		}
+	}
+	sort.Slice(pins, func(i, j int) bool { return pins[i].name < pins[j].name })
+	return pins
+}
+
+// full name wins over pin name which may match several pins
+func matchPin(pins []namedPin, key string) []namedPin {
+	matches := []namedPin{}
+	for _, pin := range pins {
+		if pin.name == key {
+			return []namedPin{pin}
+		}
+		if pin.name[strings.LastIndex(pin.name, ".")+1:] == key {
+			matches = append(matches, pin)
+		}
+	}
+	return matches
+}
+
+// describes failed match, empty if exactly one pin matched
+func matchProblem(matches []namedPin, kind string, key string) string {
+	switch len(matches) {
+	case 0:
+		return fmt.Sprintf("no %s pin %s", kind, key)
+	case 1:
+		return ""
+	}
+	names := make([]string, 0, len(matches))
+	for _, pin := range matches {
+		names = append(names, pin.name)
+	}
+	return fmt.Sprintf("ambiguous %s pin %s: %s", kind, key, strings.Join(names, ", "))
+}
+
+func returnKeys(returns map[string]Return) []string {
+	keys := make([]string, 0, len(returns))
+	for key := range returns {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// values with {$.x} references are only known when workflow runs,
+// literals around them are still checked
+func isExpression(value interface{}) bool {
+	s, ok := value.(string)
+	return ok && expression.Contains(s)
+}
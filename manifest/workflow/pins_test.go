@@ -0,0 +1,74 @@
+package workflow
+
+import (
+	"testing"
+)
+
+func TestCheckPins(t *testing.T) {
+	c := parseInstance(t, instance)
+	workflows, err := Parse(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if problems := CheckPins(c, workflows); len(problems) != 0 {
+		t.Error("No problems expected, got", problems)
+	}
+}
+
+func TestCheckPinsProblems(t *testing.T) {
+	c := parseInstance(t, `
+application:
+    components:
+        app:
+            type: workflow.Instance
+            interfaces:
+                input:
+                    size: configuration(int)
+                    zone: configuration(string)
+                    retries: configuration(int)
+                result:
+                    hosts: publish-signal(list<string>)
+                    count: publish-signal(int)
+                    status: publish-signal(string)
+                other:
+                    status: publish-signal(string)
+            configuration:
+                configuration.workflows:
+                    launch:
+                        parameters:
+                            - size: {default: 1}
+                            - flavor: {default: small}
+                            - region: {description: Required}
+                            - retries: {default: many}
+                        steps: []
+                        return:
+                            hosts: ["{$.host}", "{$.host}-backup", 1]
+                            count: many
+                            status: ok
+                            missing: 1
+`)
+	workflows, err := Parse(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		"Workflow launch: return count: value doesn't match pin result.count: Expected int, got string",
+		"Workflow launch: return hosts: value doesn't match pin result.hosts: [2]: Expected string, got int",
+		"Workflow launch: return missing: no publish-signal pin missing",
+		"Workflow launch: return status: ambiguous publish-signal pin status: other.status, result.status",
+		"Workflow launch: parameter region: no configuration pin region",
+		"Workflow launch: parameter retries: default doesn't match pin input.retries: Expected int, got string",
+		"Pin other.status is never returned by workflows",
+		"Pin result.status is never returned by workflows",
+		"Configuration pin input.zone is not a parameter of any workflow",
+	}
+	problems := CheckPins(c, workflows)
+	if len(problems) != len(expected) {
+		t.Fatalf("\nReported: %v\nExpect:   %v", problems, expected)
+	}
+	for i, problem := range problems {
+		if problem.Error() != expected[i] {
+			t.Errorf("\nReported: %v\nExpect:   %v", problem, expected[i])
+		}
+	}
+}
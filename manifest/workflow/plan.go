@@ -0,0 +1,106 @@
+package workflow
+
+import (
+	"fmt"
+	"strings"
+)
+
+// execution order of workflow steps: every step waits for all steps of
+// its preceding phases, steps of the same level can run in parallel
+type Plan struct {
+	// label of the plan, workflow name by default
+	Name  string
+	Steps []Step
+	// step name to names of steps it waits for, in order of steps
+	Dependencies map[string][]string
+	// step names by level in order of steps
+	Levels [][]string
+}
+
+// builds step graph, fails on unknown preceding phases and cycles
+func NewPlan(w Workflow) (Plan, error) {
+	if err := w.Validate(); err != nil {
+		return Plan{}, err
+	}
+	p := Plan{Name: w.Name, Steps: w.Steps, Dependencies: map[string][]string{}}
+	for _, step := range w.Steps {
+		preceding := map[string]bool{}
+		for _, phase := range step.PrecedingPhases {
+			preceding[phase] = true
+		}
+		dependencies := []string{}
+		for _, other := range w.Steps {
+			if preceding[other.Phase] {
+				dependencies = append(dependencies, other.Name)
+			}
+		}
+		p.Dependencies[step.Name] = dependencies
+	}
+	// graph is acyclic, so levels settle in at most len(steps) passes
+	levels := map[string]int{}
+	for changed := true; changed; {
+		changed = false
+		for _, step := range w.Steps {
+			level := 0
+			for _, dependency := range p.Dependencies[step.Name] {
+				if levels[dependency]+1 > level {
+					level = levels[dependency] + 1
+				}
+			}
+			if level != levels[step.Name] {
+				levels[step.Name] = level
+				changed = true
+			}
+		}
+	}
+	for _, step := range w.Steps {
+		level := levels[step.Name]
+		for len(p.Levels) <= level {
+			p.Levels = append(p.Levels, nil)
+		}
+		p.Levels[level] = append(p.Levels[level], step.Name)
+	}
+	return p, nil
+}
+
+// one line per level:
+//
+//	launch:
+//	    1: provision
+//	    2: deploy, configure
+func (p Plan) Text() string {
+	s := p.Name + ":\n"
+	if len(p.Levels) == 0 {
+		s += "    no steps\n"
+	}
+	for i, level := range p.Levels {
+		s += fmt.Sprintf("    %d: %s\n", i+1, strings.Join(level, ", "))
+	}
+	return s
+}
+
+// renders plans as Graphviz digraph, each plan in its own cluster
+func DOT(plans ...Plan) string {
+	s := "digraph plan {\n    rankdir=LR;\n"
+	for i, p := range plans {
+		s += fmt.Sprintf("    subgraph cluster_%d {\n        label=\"%s\";\n", i, dotEscape(p.Name))
+		node := func(step string) string {
+			return "\"" + dotEscape(p.Name+"/"+step) + "\""
+		}
+		for _, step := range p.Steps {
+			// step name and action on separate lines
+			s += fmt.Sprintf("        %s [label=\"%s\\n%s\"];\n", node(step.Name), dotEscape(step.Name), dotEscape(step.Action.Name))
+		}
+		for _, step := range p.Steps {
+			for _, dependency := range p.Dependencies[step.Name] {
+				s += fmt.Sprintf("        %s -> %s;\n", node(dependency), node(step.Name))
+			}
+		}
+		s += "    }\n"
+	}
+	return s + "}\n"
+}
+
+func dotEscape(s string) string {
+	return strings.Replace(strings.Replace(s, "\\", "\\\\", -1), "\"", "\\\"", -1)
+}
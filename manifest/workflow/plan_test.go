@@ -0,0 +1,61 @@
+package workflow
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func planSteps() []Step {
+	step := func(name, phase, action string, preceding ...string) Step {
+		return Step{Name: name, Phase: phase, PrecedingPhases: preceding, Action: Action{Name: action}}
+	}
+	return []Step{
+		step("vms", "provision", "provisionVms"),
+		step("db", "database", "chefsolo", "provision"),
+		step("app", "deploy", "chefsolo", "provision"),
+		step("lb", "deploy", "chefsolo", "provision"),
+		step("check", "check", "execrun", "deploy", "database"),
+		step("notify", "notify", "execrun"),
+	}
+}
+
+func TestNewPlan(t *testing.T) {
+	plan, err := NewPlan(Workflow{"launch", nil, planSteps(), nil})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedLevels := [][]string{{"vms", "notify"}, {"db", "app", "lb"}, {"check"}}
+	if !reflect.DeepEqual(plan.Levels, expectedLevels) {
+		t.Errorf("\nLevels: %v\nExpect: %v", plan.Levels, expectedLevels)
+	}
+	if !reflect.DeepEqual(plan.Dependencies["check"], []string{"db", "app", "lb"}) {
+		t.Error("Unexpected dependencies", plan.Dependencies["check"])
+	}
+	text := "launch:\n    1: vms, notify\n    2: db, app, lb\n    3: check\n"
+	if plan.Text() != text {
+		t.Errorf("\nText:   %q\nExpect: %q", plan.Text(), text)
+	}
+	dot := DOT(plan)
+	for _, line := range []string{
+		`subgraph cluster_0 {`,
+		`label="launch";`,
+		`"launch/vms" [label="vms\nprovisionVms"];`,
+		`"launch/lb" -> "launch/check";`,
+	} {
+		if !strings.Contains(dot, line) {
+			t.Errorf("%s expected in\n%s", line, dot)
+		}
+	}
+}
+
+func TestNewPlanErrors(t *testing.T) {
+	steps := append(planSteps(), Step{Name: "late", Phase: "provision", PrecedingPhases: []string{"check"}, Action: Action{Name: "x"}})
+	if _, err := NewPlan(Workflow{"launch", nil, steps, nil}); err == nil || !strings.Contains(err.Error(), "phase cycle") {
+		t.Error("Cycle error expected, got", err)
+	}
+	steps = append(planSteps(), Step{Name: "late", Phase: "late", PrecedingPhases: []string{"missing"}, Action: Action{Name: "x"}})
+	if _, err := NewPlan(Workflow{"launch", nil, steps, nil}); err == nil || !strings.Contains(err.Error(), "unknown preceding phase missing") {
+		t.Error("Missing predecessor error expected, got", err)
+	}
+}
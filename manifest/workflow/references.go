@@ -0,0 +1,294 @@
+package workflow
+
+import (
+	"fmt"
+	"github.com/chemikadze/gonomi/manifest"
+	"github.com/chemikadze/gonomi/manifest/expression"
+	"gopkg.in/yaml.v3"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+type ReferenceKind int
+
+const (
+	ParameterReference ReferenceKind = iota
+	OutputReference
+	PinReference
+)
+
+func (k ReferenceKind) String() string {
+	switch k {
+	case ParameterReference:
+		return "parameter"
+	case OutputReference:
+		return "output"
+	}
+	return "pin"
+}
+
+// {$.x} reference of workflow and what it refers to
+type ResolvedReference struct {
+	// where reference is used, e.g. step deploy: parameters.roles[0]
+	Location  string
+	Reference expression.Reference
+	Kind      ReferenceKind
+	// parameter name, step producing output or interface.pin
+	Target string
+}
+
+// reference which can't be resolved or parsed at its location
+type ReferenceError struct {
+	Workflow string
+	Location string
+	// byte offset in string value
+	Offset  int
+	Message string
+	// position of reference in manifest, set by Locate
+	Line   int
+	Column int
+	// keys and indices leading to string from workflow
+	path []interface{}
+}
+
+func (e ReferenceError) Error() string {
+	if e.Line != 0 {
+		return fmt.Sprintf("%d:%d: Workflow %s: %s: %s", e.Line, e.Column, e.Workflow, e.Location, e.Message)
+	}
+	return fmt.Sprintf("Workflow %s: %s: column %d: %s", e.Workflow, e.Location, e.Offset+1, e.Message)
+}
+
+// resolves references in step parameters and returns against workflow
+// parameters, outputs of steps of preceding phases and consume-signal or
+// configuration pins of component referred as $.interface.pin
+func (w Workflow) Resolve(c manifest.LeafComponent) ([]ResolvedReference, []error) {
+	resolved := []ResolvedReference{}
+	problems := []error{}
+	parameters := map[string]bool{}
+	for _, parameter := range w.Parameters {
+		parameters[parameter.Name] = true
+	}
+	outputs := map[string]Step{}
+	for _, step := range w.Steps {
+		for variable := range step.Output {
+			outputs[variable] = step
+		}
+	}
+	resolve := func(location string, path []interface{}, s string, preceding map[string]bool) {
+		t, err := expression.Parse(s)
+		if err != nil {
+			if err, ok := err.(expression.SyntaxError); ok {
+				problems = append(problems, ReferenceError{w.Name, location, err.Offset, err.Message, 0, 0, path})
+			}
+			return
+		}
+		for _, reference := range t.References() {
+			root := reference.Root()
+			if parameters[root] {
+				resolved = append(resolved, ResolvedReference{location, reference, ParameterReference, root})
+			} else if step, ok := outputs[root]; ok {
+				if preceding != nil && !preceding[step.Phase] {
+					problems = append(problems, ReferenceError{w.Name, location, reference.Offset,
+						fmt.Sprintf("output %s of step %s is not available, phase %s does not precede", root, step.Name, step.Phase), 0, 0, path})
+					continue
+				}
+				resolved = append(resolved, ResolvedReference{location, reference, OutputReference, step.Name})
+			} else if iface, ok := c.Interfaces[root]; ok {
+				if len(reference.Path) < 2 || reference.Path[1].IsIndex {
+					problems = append(problems, ReferenceError{w.Name, location, reference.Offset, fmt.Sprintf("reference %s to interface has no pin name", reference), 0, 0, path})
+					continue
+				}
+				pin, ok := iface.Pins[reference.Path[1].Name]
+				if !ok {
+					problems = append(problems, ReferenceError{w.Name, location, reference.Offset, fmt.Sprintf("no pin %s in interface %s", reference.Path[1].Name, root), 0, 0, path})
+					continue
+				}
+				if _, ok := pin.PinType.(manifest.CommandPin); ok || pin.Direction.IsSend() && !isConfiguration(pin) {
+					problems = append(problems, ReferenceError{w.Name, location, reference.Offset, fmt.Sprintf("pin %s.%s can't be read by workflow", root, reference.Path[1].Name), 0, 0, path})
+					continue
+				}
+				resolved = append(resolved, ResolvedReference{location, reference, PinReference, root + "." + reference.Path[1].Name})
+			} else {
+				problems = append(problems, ReferenceError{w.Name, location, reference.Offset, fmt.Sprintf("unresolved reference %s", reference), 0, 0, path})
+			}
+		}
+	}
+	for _, step := range w.Steps {
+		preceding := w.precedingPhases(step.Phase)
+		walkStrings(step.Action.Parameters, []interface{}{"steps", step.Name, "parameters"}, func(path []interface{}, s string) {
+			resolve("step "+step.Name+": "+location(path[2:]), path, s, preceding)
+		})
+	}
+	for _, key := range returnKeys(w.Return) {
+		walkStrings(w.Return[key].Value, []interface{}{"return", key}, func(path []interface{}, s string) {
+			resolve("return "+location(path[1:]), path, s, nil)
+		})
+	}
+	return resolved, problems
+}
+
+// phases which complete before phase starts, directly or transitively
+func (w Workflow) precedingPhases(phase string) map[string]bool {
+	direct := map[string][]string{}
+	for _, step := range w.Steps {
+		direct[step.Phase] = append(direct[step.Phase], step.PrecedingPhases...)
+	}
+	result := map[string]bool{}
+	queue := append([]string{}, direct[phase]...)
+	for len(queue) != 0 {
+		next := queue[0]
+		queue = queue[1:]
+		if !result[next] {
+			result[next] = true
+			queue = append(queue, direct[next]...)
+		}
+	}
+	return result
+}
+
+func isConfiguration(pin manifest.DirectedPinType) bool {
+	_, ok := pin.PinType.(manifest.ConfigurationPin)
+	return ok
+}
+
+// calls f for every string in value with keys and indices leading to it,
+// map keys are sorted; keys which are not strings are formatted
+func walkStrings(value interface{}, path []interface{}, f func(path []interface{}, s string)) {
+	switch value := value.(type) {
+	case string:
+		f(path, value)
+	case []interface{}:
+		for i, item := range value {
+			walkStrings(item, append(path[:len(path):len(path)], i), f)
+		}
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(value))
+		for key, item := range value {
+			m[fmt.Sprint(key)] = item
+		}
+		walkStrings(m, path, f)
+	case manifest.Configuration:
+		walkStrings(map[string]interface{}(value), path, f)
+	case map[string]interface{}:
+		keys := make([]string, 0, len(value))
+		for key := range value {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			walkStrings(value[key], append(path[:len(path):len(path)], key), f)
+		}
+	}
+}
+
+// keys joined by dots and indices in brackets, e.g. parameters.nodes[0]
+func location(path []interface{}) string {
+	result := ""
+	for _, item := range path {
+		switch item := item.(type) {
+		case int:
+			result += fmt.Sprintf("[%d]", item)
+		default:
+			if result != "" {
+				result += "."
+			}
+			result += fmt.Sprint(item)
+		}
+	}
+	return result
+}
+
+// sets Line and Column of reference errors of workflows of component
+// from manifest source, Column points at the reference itself when the
+// string is written on one line; other errors are returned as they are
+func Locate(src string, component manifest.ComponentId, problems []error) []error {
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(src), &doc); err != nil || len(doc.Content) == 0 {
+		return problems
+	}
+	node := mappingValue(doc.Content[0], "application")
+	for _, name := range component.Path {
+		node = mappingValue(mappingValue(node, "components"), name)
+	}
+	workflows := mappingValue(mappingValue(node, "configuration"), WorkflowsKey)
+	lines := strings.Split(src, "\n")
+	result := make([]error, 0, len(problems))
+	for _, problem := range problems {
+		if e, ok := problem.(ReferenceError); ok && len(e.path) >= 2 {
+			n := find(mappingValue(workflows, e.Workflow), e.path[:2])
+			// return value is either given as is or as value of its spec
+			if e.path[0] == "return" && mappingValue(n, "value") != nil {
+				n = mappingValue(n, "value")
+			}
+			if n = find(n, e.path[2:]); n != nil {
+				e.Line, e.Column = n.Line, n.Column+referenceColumn(lines[n.Line-1], n, e.Offset)
+			}
+			problem = e
+		}
+		result = append(result, problem)
+	}
+	return result
+}
+
+// node at keys and indices, steps given as list of single-key
+// mappings are found by key as well
+func find(node *yaml.Node, path []interface{}) *yaml.Node {
+	for _, item := range path {
+		if node == nil {
+			return nil
+		}
+		switch item := item.(type) {
+		case int:
+			if node.Kind != yaml.SequenceNode || item >= len(node.Content) {
+				return nil
+			}
+			node = node.Content[item]
+		default:
+			key := fmt.Sprint(item)
+			if node.Kind == yaml.SequenceNode {
+				var found *yaml.Node
+				for _, entry := range node.Content {
+					if value := mappingValue(entry, key); value != nil {
+						found = value
+					}
+				}
+				node = found
+			} else {
+				node = mappingValue(node, key)
+			}
+		}
+	}
+	return node
+}
+
+// columns between start of scalar and byte offset in its value, 0 when
+// scalar isn't written on the line as is, e.g. folded or escaped
+func referenceColumn(line string, node *yaml.Node, offset int) int {
+	quote := 0
+	if node.Style&(yaml.DoubleQuotedStyle|yaml.SingleQuotedStyle) != 0 {
+		quote = 1
+	}
+	runes := []rune(line)
+	start := node.Column - 1 + quote
+	if offset > len(node.Value) || start > len(runes) {
+		return 0
+	}
+	prefix := node.Value[:offset]
+	if !strings.HasPrefix(string(runes[start:]), prefix) {
+		return 0
+	}
+	return quote + utf8.RuneCountInString(prefix)
+}
+
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
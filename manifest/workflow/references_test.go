@@ -0,0 +1,130 @@
+package workflow
+
+import (
+	"github.com/chemikadze/gonomi/manifest"
+	"reflect"
+	"testing"
+)
+
+func TestResolve(t *testing.T) {
+	c := parseInstance(t, `
+application:
+    components:
+        app:
+            type: workflow.Instance
+            interfaces:
+                input:
+                    port: configuration(int)
+                db:
+                    url: consume-signal(string)
+                result:
+                    hosts: publish-signal(list<string>)
+            configuration:
+                configuration.workflows:
+                    launch:
+                        parameters:
+                            - size: 1
+                        steps:
+                            - provision:
+                                  action: provisionVms
+                                  parameters:
+                                      targetQuantity: "{$.size}"
+                                      early: "{$.attrs}"
+                                  output:
+                                      hosts: ips
+                            - deploy:
+                                  action: chefsolo
+                                  precedingPhases: [provision]
+                                  parameters:
+                                      nodes: ["{$.hosts[0]}", "{$.missing}"]
+                                      url: "{$.db.url}:{$.input.port}"
+                                      bad: "{$.result.hosts} {$.db.nope}"
+                                      worse: "{$.x"
+                                  output:
+                                      attrs: chefState
+                        return:
+                            hosts:
+                                value: "{$.attrs.hosts}"
+`)
+	workflows, err := Parse(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resolved, problems := workflows["launch"].Resolve(c)
+	targets := []string{}
+	for _, r := range resolved {
+		targets = append(targets, r.Location+" "+r.Kind.String()+" "+r.Target)
+	}
+	expectedTargets := []string{
+		"step provision: parameters.targetQuantity parameter size",
+		"step deploy: parameters.nodes[0] output provision",
+		"step deploy: parameters.url pin db.url",
+		"step deploy: parameters.url pin input.port",
+		"return hosts output deploy",
+	}
+	if !reflect.DeepEqual(targets, expectedTargets) {
+		t.Errorf("\nResolved: %v\nExpect:   %v", targets, expectedTargets)
+	}
+	messages := []string{}
+	for _, problem := range problems {
+		messages = append(messages, problem.Error())
+	}
+	expectedProblems := []string{
+		"Workflow launch: step provision: parameters.early: column 1: output attrs of step deploy is not available, phase deploy does not precede",
+		"Workflow launch: step deploy: parameters.bad: column 1: pin result.hosts can't be read by workflow",
+		"Workflow launch: step deploy: parameters.bad: column 18: no pin nope in interface db",
+		"Workflow launch: step deploy: parameters.nodes[1]: column 1: unresolved reference $.missing",
+		"Workflow launch: step deploy: parameters.worse: column 1: unterminated expression",
+	}
+	if !reflect.DeepEqual(messages, expectedProblems) {
+		t.Errorf("\nReported: %v\nExpect:   %v", messages, expectedProblems)
+	}
+}
+
+const located = `application:
+    components:
+        app:
+            type: workflow.Instance
+            configuration:
+                configuration.workflows:
+                    launch:
+                        parameters:
+                            - size: 1
+                        steps:
+                            - deploy:
+                                  action: chefsolo
+                                  parameters:
+                                      codes: {1: "ok {$.missing}", 2: "{$.size}"}
+                                      nodes:
+                                          - "{$.size}"
+                                          - x {$.nope}
+                        return:
+                            url:
+                                value: "{$.other}"
+                            port: >
+                                {$.folded}
+`
+
+// references in maps with keys other than strings are checked too,
+// positions point at references in manifest
+func TestLocate(t *testing.T) {
+	c := parseInstance(t, located)
+	workflows, err := Parse(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, problems := workflows["launch"].Resolve(c)
+	messages := []string{}
+	for _, problem := range Locate(located, manifest.ComponentId{[]string{"app"}}, problems) {
+		messages = append(messages, problem.Error())
+	}
+	expected := []string{
+		"14:54: Workflow launch: step deploy: parameters.codes.1: unresolved reference $.missing",
+		"17:47: Workflow launch: step deploy: parameters.nodes[1]: unresolved reference $.nope",
+		"21:35: Workflow launch: return port: unresolved reference $.folded",
+		"20:41: Workflow launch: return url: unresolved reference $.other",
+	}
+	if !reflect.DeepEqual(messages, expected) {
+		t.Errorf("\nReported: %q\nExpect:   %q", messages, expected)
+	}
+}
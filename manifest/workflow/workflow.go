@@ -0,0 +1,384 @@
+// Package workflow gives typed view of workflow.Instance components whose
+// behavior is described by workflows in their configuration:
+//
+//	configuration:
+//	    configuration.workflows:
+//	        launch:
+//	            parameters:
+//	                - size: {description: Number of nodes, default: 1}
+//	            steps:
+//	                - provision:
+//	                      action: provisionVms
+//	                      parameters: {targetQuantity: "{$.size}"}
+//	                      output: {hosts: ips}
+//	                - deploy:
+//	                      action: chefsolo
+//	                      precedingPhases: [provision]
+//	            return:
+//	                hosts: {value: "{$.hosts}", description: Node addresses}
+package workflow
+
+import (
+	"errors"
+	"fmt"
+	"github.com/chemikadze/gonomi/manifest"
+	"github.com/chemikadze/gonomi/manifest/parsing"
+	"sort"
+	"strings"
+)
+
+const (
+	InstanceTypeName = "workflow.Instance"
+	WorkflowsKey     = "configuration.workflows"
+)
+
+type Workflow struct {
+	Name       string
+	Parameters Parameters
+	Steps      []Step
+	// by pin name
+	Return map[string]Return
+}
+
+// parameters workflow is launched with, in declaration order
+type Parameters []Parameter
+
+type Parameter struct {
+	Name        string
+	Description string
+	Default     interface{}
+}
+
+type Step struct {
+	Name string
+	// phase step belongs to, defaults to step name
+	Phase           string
+	PrecedingPhases []string
+	Action          Action
+	// step variable name to action output name
+	Output map[string]string
+}
+
+type Action struct {
+	Name       string
+	Parameters map[string]interface{}
+}
+
+type Return struct {
+	Value       interface{}
+	Description string
+}
+
+// parses and validates workflows of workflow.Instance component by name,
+// components of other types have no workflows
+func Parse(c manifest.LeafComponent) (map[string]Workflow, error) {
+	if c.Type.Name != InstanceTypeName {
+		return nil, nil
+	}
+	repr, ok := c.Configuration[WorkflowsKey]
+	if !ok {
+		return nil, nil
+	}
+	entries, ok := toMap(repr)
+	if !ok {
+		return nil, parsing.ManifestError{fmt.Sprintf("Expected mapping of workflows in %s, got: %v", WorkflowsKey, repr), 0, 0}
+	}
+	workflows := make(map[string]Workflow, len(entries))
+	for _, name := range sortedKeys(entries) {
+		w, err := ParseWorkflow(name, entries[name])
+		if err != nil {
+			return nil, err
+		}
+		if err := w.Validate(); err != nil {
+			return nil, err
+		}
+		workflows[name] = w
+	}
+	return workflows, nil
+}
+
+func ParseWorkflow(name string, repr interface{}) (Workflow, error) {
+	body, ok := toMap(repr)
+	if !ok {
+		return Workflow{}, workflowError(name, "expected mapping, got: %v", repr)
+	}
+	w := Workflow{Name: name}
+	var err error
+	if w.Parameters, err = parseParameters(body["parameters"]); err != nil {
+		return Workflow{}, workflowError(name, "%s", err)
+	}
+	steps, err := namedEntries(body["steps"])
+	if err != nil {
+		return Workflow{}, workflowError(name, "steps: %s", err)
+	}
+	for _, entry := range steps {
+		step, err := parseStep(entry.name, entry.value)
+		if err != nil {
+			return Workflow{}, workflowError(name, "step %s: %s", entry.name, err)
+		}
+		w.Steps = append(w.Steps, step)
+	}
+	if w.Return, err = parseReturn(body["return"]); err != nil {
+		return Workflow{}, workflowError(name, "%s", err)
+	}
+	return w, nil
+}
+
+// checks that step names are unique and preceding phases exist
+// and do not depend on each other in a cycle
+func (w Workflow) Validate() error {
+	names := map[string]bool{}
+	dependencies := map[string][]string{}
+	for _, step := range w.Steps {
+		if names[step.Name] {
+			return workflowError(w.Name, "duplicate step %s", step.Name)
+		}
+		names[step.Name] = true
+		dependencies[step.Phase] = append(dependencies[step.Phase], step.PrecedingPhases...)
+	}
+	for _, step := range w.Steps {
+		for _, phase := range step.PrecedingPhases {
+			if _, ok := dependencies[phase]; !ok {
+				return workflowError(w.Name, "step %s: unknown preceding phase %s", step.Name, phase)
+			}
+			if phase == step.Phase {
+				return workflowError(w.Name, "step %s: phase %s precedes itself", step.Name, phase)
+			}
+		}
+	}
+	state := map[string]int{}
+	var visit func(phase string, path []string) error
+	visit = func(phase string, path []string) error {
+		switch state[phase] {
+		case visiting:
+			return workflowError(w.Name, "phase cycle: %s", strings.Join(append(path, phase), " -> "))
+		case visited:
+			return nil
+		}
+		state[phase] = visiting
+		for _, preceding := range dependencies[phase] {
+			if err := visit(preceding, append(path, phase)); err != nil {
+				return err
+			}
+		}
+		state[phase] = visited
+		return nil
+	}
+	for _, phase := range w.Phases() {
+		if err := visit(phase, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// phases of steps in order of appearance
+func (w Workflow) Phases() []string {
+	seen := map[string]bool{}
+	phases := []string{}
+	for _, step := range w.Steps {
+		if !seen[step.Phase] {
+			seen[step.Phase] = true
+			phases = append(phases, step.Phase)
+		}
+	}
+	return phases
+}
+
+const (
+	visiting = iota + 1
+	visited
+)
+
+func parseParameters(repr interface{}) (Parameters, error) {
+	entries, err := namedEntries(repr)
+	if err != nil {
+		return nil, errors.New("parameters: " + err.Error())
+	}
+	parameters := Parameters{}
+	for _, entry := range entries {
+		parameter := Parameter{Name: entry.name}
+		if spec, ok := toMap(entry.value); ok {
+			if parameter.Description, err = optionalString(spec, "description"); err != nil {
+				return nil, errors.New(fmt.Sprintf("parameter %s: %s", entry.name, err))
+			}
+			parameter.Default = spec["default"]
+		} else {
+			// short form is just default value
+			parameter.Default = entry.value
+		}
+		parameters = append(parameters, parameter)
+	}
+	return parameters, nil
+}
+
+func parseStep(name string, repr interface{}) (Step, error) {
+	body, ok := toMap(repr)
+	if !ok {
+		return Step{}, errors.New(fmt.Sprintf("expected mapping, got: %v", repr))
+	}
+	step := Step{Name: name, Phase: name}
+	action, err := optionalString(body, "action")
+	if err != nil {
+		return Step{}, err
+	}
+	if action == "" {
+		return Step{}, errors.New("action is not specified")
+	}
+	step.Action.Name = action
+	if phase, err := optionalString(body, "phase"); err != nil {
+		return Step{}, err
+	} else if phase != "" {
+		step.Phase = phase
+	}
+	if step.PrecedingPhases, err = stringList(body["precedingPhases"]); err != nil {
+		return Step{}, errors.New("precedingPhases: " + err.Error())
+	}
+	if repr, ok := body["parameters"]; ok && repr != nil {
+		parameters, ok := toMap(repr)
+		if !ok {
+			return Step{}, errors.New(fmt.Sprintf("parameters: expected mapping, got: %v", repr))
+		}
+		step.Action.Parameters = parameters
+	}
+	if repr, ok := body["output"]; ok && repr != nil {
+		output, ok := toMap(repr)
+		if !ok {
+			return Step{}, errors.New(fmt.Sprintf("output: expected mapping, got: %v", repr))
+		}
+		step.Output = make(map[string]string, len(output))
+		for variable, value := range output {
+			source, ok := value.(string)
+			if !ok {
+				return Step{}, errors.New(fmt.Sprintf("output %s: expected name of action output, got: %v", variable, value))
+			}
+			step.Output[variable] = source
+		}
+	}
+	return step, nil
+}
+
+func parseReturn(repr interface{}) (map[string]Return, error) {
+	if repr == nil {
+		return nil, nil
+	}
+	entries, ok := toMap(repr)
+	if !ok {
+		return nil, errors.New(fmt.Sprintf("return: expected mapping, got: %v", repr))
+	}
+	result := make(map[string]Return, len(entries))
+	for pin, value := range entries {
+		r := Return{Value: value}
+		if spec, ok := toMap(value); ok {
+			if _, ok := spec["value"]; ok {
+				description, err := optionalString(spec, "description")
+				if err != nil {
+					return nil, errors.New(fmt.Sprintf("return %s: %s", pin, err))
+				}
+				r = Return{spec["value"], description}
+			}
+		}
+		result[pin] = r
+	}
+	return result, nil
+}
+
+type namedEntry struct {
+	name  string
+	value interface{}
+}
+
+// accepts list of single-key mappings keeping the order,
+// or mapping which is ordered by key
+func namedEntries(repr interface{}) ([]namedEntry, error) {
+	switch repr := repr.(type) {
+	case nil:
+		return nil, nil
+	case []interface{}:
+		entries := make([]namedEntry, 0, len(repr))
+		for _, item := range repr {
+			m, ok := toMap(item)
+			if !ok || len(m) != 1 {
+				return nil, errors.New(fmt.Sprintf("expected single-key mapping, got: %v", item))
+			}
+			for name, value := range m {
+				entries = append(entries, namedEntry{name, value})
+			}
+		}
+		return entries, nil
+	}
+	m, ok := toMap(repr)
+	if !ok {
+		return nil, errors.New(fmt.Sprintf("expected list or mapping, got: %v", repr))
+	}
+	entries := make([]namedEntry, 0, len(m))
+	for _, name := range sortedKeys(m) {
+		entries = append(entries, namedEntry{name, m[name]})
+	}
+	return entries, nil
+}
+
+// mapping with string keys as decoded from YAML or given in Go code
+func toMap(repr interface{}) (map[string]interface{}, bool) {
+	switch repr := repr.(type) {
+	case map[string]interface{}:
+		return repr, true
+	case manifest.Configuration:
+		return repr, true
+	case map[interface{}]interface{}:
+		result := make(map[string]interface{}, len(repr))
+		for key, value := range repr {
+			name, ok := key.(string)
+			if !ok {
+				return nil, false
+			}
+			result[name] = value
+		}
+		return result, true
+	}
+	return nil, false
+}
+
+func optionalString(m map[string]interface{}, key string) (string, error) {
+	value, ok := m[key]
+	if !ok || value == nil {
+		return "", nil
+	}
+	s, ok := value.(string)
+	if !ok {
+		return "", errors.New(fmt.Sprintf("%s: expected string, got: %v", key, value))
+	}
+	return s, nil
+}
+
+func stringList(repr interface{}) ([]string, error) {
+	if repr == nil {
+		return nil, nil
+	}
+	items, ok := repr.([]interface{})
+	if !ok {
+		return nil, errors.New(fmt.Sprintf("expected list, got: %v", repr))
+	}
+	result := make([]string, 0, len(items))
+	for _, item := range items {
+		s, ok := item.(string)
+		if !ok {
+			return nil, errors.New(fmt.Sprintf("expected string, got: %v", item))
+		}
+		result = append(result, s)
+	}
+	return result, nil
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func workflowError(name string, format string, args ...interface{}) error {
+	return parsing.ManifestError{fmt.Sprintf("Workflow %s: ", name) + fmt.Sprintf(format, args...), 0, 0}
+}
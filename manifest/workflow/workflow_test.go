@@ -0,0 +1,134 @@
+package workflow
+
+import (
+	"github.com/chemikadze/gonomi/manifest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const instance = `
+application:
+    components:
+        app:
+            type: workflow.Instance
+            interfaces:
+                input:
+                    size: configuration(int)
+                result:
+                    hosts: publish-signal(list<string>)
+                    status: publish-signal(string)
+            configuration:
+                configuration.workflows:
+                    launch:
+                        parameters:
+                            - size:
+                                  description: Number of nodes
+                                  default: 1
+                            - flavor: small
+                        steps:
+                            - provision:
+                                  action: provisionVms
+                                  parameters:
+                                      targetQuantity: "{$.size}"
+                                  output:
+                                      hosts: ips
+                            - deploy:
+                                  action: chefsolo
+                                  precedingPhases: [provision]
+                                  parameters:
+                                      roles: [default]
+                        return:
+                            hosts:
+                                value: "{$.hosts}"
+                                description: Node addresses
+                            status: ok
+                    destroy:
+                        steps: {}
+`
+
+func parseInstance(t *testing.T, src string) manifest.LeafComponent {
+	app, err := manifest.Parse(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return app.Components["app"].(manifest.LeafComponent)
+}
+
+func TestParse(t *testing.T) {
+	workflows, err := Parse(parseInstance(t, instance))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(workflows) != 2 {
+		t.Fatal("Two workflows expected, got", workflows)
+	}
+	launch := workflows["launch"]
+	expectedParameters := Parameters{{"size", "Number of nodes", 1}, {"flavor", "", "small"}}
+	if !reflect.DeepEqual(launch.Parameters, expectedParameters) {
+		t.Errorf("\nParameters: %v\nExpect:     %v", launch.Parameters, expectedParameters)
+	}
+	expectedSteps := []Step{
+		{"provision", "provision", nil, Action{"provisionVms", map[string]interface{}{"targetQuantity": "{$.size}"}}, map[string]string{"hosts": "ips"}},
+		{"deploy", "deploy", []string{"provision"}, Action{"chefsolo", map[string]interface{}{"roles": []interface{}{"default"}}}, nil},
+	}
+	if !reflect.DeepEqual(launch.Steps, expectedSteps) {
+		t.Errorf("\nSteps:  %v\nExpect: %v", launch.Steps, expectedSteps)
+	}
+	expectedReturn := map[string]Return{"hosts": {"{$.hosts}", "Node addresses"}, "status": {"ok", ""}}
+	if !reflect.DeepEqual(launch.Return, expectedReturn) {
+		t.Errorf("\nReturn: %v\nExpect: %v", launch.Return, expectedReturn)
+	}
+	if len(workflows["destroy"].Steps) != 0 {
+		t.Error("Destroy workflow has no steps, got", workflows["destroy"])
+	}
+}
+
+func TestParseOtherComponent(t *testing.T) {
+	workflows, err := Parse(manifest.LeafComponent{Type: manifest.Type{"test.Component"}})
+	if err != nil || workflows != nil {
+		t.Error("No workflows expected, got", workflows, err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	step := func(name, phase string, preceding ...string) Step {
+		return Step{Name: name, Phase: phase, PrecedingPhases: preceding, Action: Action{Name: "noop"}}
+	}
+	cases := map[string][]Step{
+		"duplicate step a":                  {step("a", "a"), step("a", "b")},
+		"step b: unknown preceding phase x": {step("a", "a"), step("b", "b", "x")},
+		"step a: phase a precedes itself":   {step("a", "a", "a")},
+		"phase cycle: a -> c -> b -> a":     {step("a", "a", "c"), step("b", "b", "a"), step("c", "c", "b")},
+	}
+	for expected, steps := range cases {
+		err := Workflow{"launch", nil, steps, nil}.Validate()
+		if err == nil || err.Error() != "Workflow launch: "+expected {
+			t.Errorf("\nRaised: %v\nExpect: %v", err, expected)
+		}
+	}
+	// steps of the same phase run together
+	valid := Workflow{"launch", nil, []Step{step("a", "setup"), step("b", "setup"), step("c", "deploy", "setup")}, nil}
+	if err := valid.Validate(); err != nil {
+		t.Error(err)
+	}
+	if !reflect.DeepEqual(valid.Phases(), []string{"setup", "deploy"}) {
+		t.Error("Unexpected phases", valid.Phases())
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	cases := map[string]interface{}{
+		"expected mapping":                   "launch",
+		"step a: action is not specified":    map[interface{}]interface{}{"steps": []interface{}{map[interface{}]interface{}{"a": map[interface{}]interface{}{}}}},
+		"steps: expected single-key mapping": map[interface{}]interface{}{"steps": []interface{}{"a"}},
+		"step a: precedingPhases":            map[interface{}]interface{}{"steps": []interface{}{map[interface{}]interface{}{"a": map[interface{}]interface{}{"action": "x", "precedingPhases": "b"}}}},
+		"parameter a: description":           map[interface{}]interface{}{"parameters": []interface{}{map[interface{}]interface{}{"a": map[interface{}]interface{}{"description": 1}}}},
+	}
+	for expected, repr := range cases {
+		_, err := ParseWorkflow("launch", repr)
+		if err == nil || !strings.HasPrefix(err.Error(), "Workflow launch: "+expected) {
+			t.Errorf("\nRaised: %v\nExpect: %v", err, expected)
+		}
+	}
+}
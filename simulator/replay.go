@@ -0,0 +1,166 @@
+package simulator
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/chemikadze/gonomi/manifest"
+	"github.com/chemikadze/gonomi/manifest/datatype"
+	"strconv"
+)
+
+// feeds recorded trace into given components, the rest of components
+// are replaced by stubs: their signals and incoming calls are taken from the trace
+// and calls to them are answered with recorded results; should be called before Run
+func (s *Simulator) Replay(trace []Event, components ...string) error {
+	subset := make(map[string]bool)
+	for _, path := range components {
+		if _, ok := s.components[path]; !ok {
+			return errors.New(fmt.Sprintf("Unknown leaf component: %s", path))
+		}
+		subset[path] = true
+	}
+	for path, c := range s.components {
+		if !subset[path] {
+			c.handler = nil
+			c.stub = true
+		}
+	}
+	s.recorded = make(map[endpoint][]Event)
+	for i, e := range trace {
+		from := endpoint{e.Component, e.Pin}
+		if _, ok := s.components[e.Component]; !ok {
+			return errors.New(fmt.Sprintf("Event %d: unknown leaf component: %s", i, e.Component))
+		}
+		switch e.Kind {
+		case InjectEvent:
+			if !subset[e.Component] {
+				continue
+			}
+			pin, err := s.components[e.Component].signalPin(e.Pin, manifest.Receives)
+			if err != nil {
+				return errors.New(fmt.Sprintf("Event %d: %s", i, err))
+			}
+			if err := s.Inject(e.Component, e.Pin, coerce(pin.DataType, e.Payload)); err != nil {
+				return errors.New(fmt.Sprintf("Event %d: %s", i, err))
+			}
+		case SignalEvent:
+			if subset[e.Component] {
+				continue
+			}
+			for _, to := range s.routes[from] {
+				if !subset[to.Component] {
+					continue
+				}
+				pin, err := s.components[to.Component].signalPin(to.Pin, manifest.Receives)
+				if err != nil {
+					return errors.New(fmt.Sprintf("Event %d: %s", i, err))
+				}
+				value := coerce(pin.DataType, e.Payload)
+				if err := s.checkSignal(to, value); err != nil {
+					return errors.New(fmt.Sprintf("Event %d: %s", i, err))
+				}
+				s.enqueue(message{target: to, value: value})
+			}
+		case ResultEvent:
+			if subset[e.Component] {
+				s.recorded[from] = append(s.recorded[from], e)
+			}
+		case CommandEvent:
+			if subset[e.Component] {
+				continue
+			}
+			targets := s.routes[from]
+			if len(targets) != 1 || !subset[targets[0].Component] {
+				continue
+			}
+			to := targets[0]
+			command, err := s.components[to.Component].commandPin(to.Pin, manifest.Receives)
+			if err != nil {
+				return errors.New(fmt.Sprintf("Event %d: %s", i, err))
+			}
+			args, _ := coerce(command.Arguments, e.Payload).(map[string]interface{})
+			// nobody waits for the result of replayed call
+			s.enqueue(message{target: to, value: args, reply: make(chan reply, 1)})
+		default:
+			return errors.New(fmt.Sprintf("Event %d: unknown kind: %s", i, e.Kind))
+		}
+	}
+	return nil
+}
+
+// answers call to stubbed component with the next recorded result
+func (s *Simulator) recordedCall(from endpoint, command manifest.CommandPin) reply {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	calls := s.recorded[from]
+	if len(calls) == 0 {
+		return reply{nil, errors.New(fmt.Sprintf("%s: no recorded result", from))}
+	}
+	s.recorded[from] = calls[1:]
+	if calls[0].Error != "" {
+		return reply{nil, errors.New(calls[0].Error)}
+	}
+	result, _ := coerce(command.Result, calls[0].Result).(map[string]interface{})
+	return reply{result, nil}
+}
+
+// converts value decoded from JSON trace back to Go types of data type
+func coerce(t datatype.DataType, value interface{}) interface{} {
+	switch t := t.(type) {
+	case datatype.Int:
+		switch v := value.(type) {
+		case json.Number:
+			if i, err := strconv.Atoi(string(v)); err == nil {
+				return i
+			}
+		case float64:
+			if v == float64(int(v)) {
+				return int(v)
+			}
+		}
+	case datatype.List:
+		if v, ok := value.([]interface{}); ok {
+			result := make([]interface{}, len(v))
+			for i, element := range v {
+				result[i] = coerce(t.ElementDataType, element)
+			}
+			return result
+		}
+	case datatype.Map:
+		if v, ok := value.(map[string]interface{}); ok {
+			result := make(map[interface{}]interface{}, len(v))
+			for key, element := range v {
+				result[coerceKey(t.KeyDataType, key)] = coerce(t.ValueDataType, element)
+			}
+			return result
+		}
+	case datatype.Record:
+		if v, ok := value.(map[string]interface{}); ok {
+			result := make(map[string]interface{}, len(v))
+			for key, element := range v {
+				result[key] = coerce(t.Fields[key], element)
+			}
+			return result
+		}
+		if value == nil {
+			return map[string]interface{}(nil)
+		}
+	}
+	return value
+}
+
+// JSON object keys are always strings
+func coerceKey(t datatype.DataType, key string) interface{} {
+	switch t.(type) {
+	case datatype.Int:
+		if i, err := strconv.Atoi(key); err == nil {
+			return i
+		}
+	case datatype.Bool:
+		if b, err := strconv.ParseBool(key); err == nil {
+			return b
+		}
+	}
+	return key
+}
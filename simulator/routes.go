@@ -0,0 +1,35 @@
+package simulator
+
+import (
+	"github.com/chemikadze/gonomi/manifest"
+)
+
+// pin of a particular component instance, comparable version of manifest.Endpoint
+type endpoint struct {
+	Component string
+	Pin       manifest.PinId
+}
+
+func (e endpoint) String() string {
+	return e.Component + "#" + e.Pin.Interface + "." + e.Pin.Pin
+}
+
+func toEndpoint(e manifest.Endpoint) endpoint {
+	return endpoint{e.Component.String(), e.Pin}
+}
+
+// collects leaf components and routes between their pins from flattened application
+func collect(app manifest.Application, leaves map[string]*component, routes map[endpoint][]endpoint) error {
+	graph, err := manifest.Flatten(app)
+	if err != nil {
+		return err
+	}
+	for _, c := range graph.Components {
+		leaves[c.Id.String()] = &component{id: c.Id, path: c.Id.String(), leaf: c.Component}
+	}
+	for _, connection := range graph.Connections {
+		from := toEndpoint(connection.From)
+		routes[from] = append(routes[from], toEndpoint(connection.To))
+	}
+	return nil
+}
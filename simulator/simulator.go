@@ -0,0 +1,537 @@
+// Package simulator runs an Application in-process: leaf components are
+// backed by Go handlers, published signals are routed along bindings to
+// consume-signal pins and commands are dispatched to receive-command pins.
+package simulator
+
+import (
+	"errors"
+	"fmt"
+	"github.com/chemikadze/gonomi/manifest"
+	"github.com/chemikadze/gonomi/manifest/constants"
+	"github.com/chemikadze/gonomi/manifest/datatype"
+	"sort"
+	"sync"
+	"time"
+)
+
+type Mode int
+
+const (
+	// every component handler runs in its own goroutine
+	Concurrent Mode = iota
+	// handlers run one at a time, messages delivered in FIFO order
+	Deterministic
+)
+
+// behavior of a leaf component
+type Handler interface {
+	// called once when simulation starts
+	Start(c *Context) error
+	// called for every value arriving to consume-signal pin
+	Signal(c *Context, pin manifest.PinId, value interface{}) error
+	// called for every invocation of receive-command pin
+	Command(c *Context, pin manifest.PinId, args map[string]interface{}) (map[string]interface{}, error)
+}
+
+// Handler built from optional functions
+type HandlerFuncs struct {
+	OnStart   func(c *Context) error
+	OnSignal  func(c *Context, pin manifest.PinId, value interface{}) error
+	OnCommand func(c *Context, pin manifest.PinId, args map[string]interface{}) (map[string]interface{}, error)
+}
+
+func (h HandlerFuncs) Start(c *Context) error {
+	if h.OnStart == nil {
+		return nil
+	}
+	return h.OnStart(c)
+}
+
+func (h HandlerFuncs) Signal(c *Context, pin manifest.PinId, value interface{}) error {
+	if h.OnSignal == nil {
+		return nil
+	}
+	return h.OnSignal(c, pin, value)
+}
+
+func (h HandlerFuncs) Command(c *Context, pin manifest.PinId, args map[string]interface{}) (map[string]interface{}, error) {
+	if h.OnCommand == nil {
+		return nil, errors.New(fmt.Sprintf("Command %s.%s is not handled", pin.Interface, pin.Pin))
+	}
+	return h.OnCommand(c, pin, args)
+}
+
+type component struct {
+	id      manifest.ComponentId
+	path    string
+	leaf    manifest.LeafComponent
+	handler Handler
+	inbox   *mailbox
+	// replaced by recorded trace on replay
+	stub bool
+}
+
+type message struct {
+	target endpoint
+	value  interface{}
+	// set for command invocations only
+	reply chan reply
+}
+
+type reply struct {
+	result map[string]interface{}
+	err    error
+}
+
+type Simulator struct {
+	mode       Mode
+	components map[string]*component
+	routes     map[endpoint][]endpoint
+	started    bool
+	tracer     Tracer
+	// command results of stubbed components by caller pin
+	recorded map[endpoint][]Event
+
+	mu      sync.Mutex
+	idle    *sync.Cond
+	pending int
+	err     error
+	// deterministic mode delivery queue
+	queue []message
+}
+
+// prepares simulation of app, handlers are attached with Handle;
+// constants components get handler publishing their values
+func New(app manifest.Application, mode Mode) (*Simulator, error) {
+	s := &Simulator{
+		mode:       mode,
+		components: make(map[string]*component),
+		routes:     make(map[endpoint][]endpoint),
+	}
+	s.idle = sync.NewCond(&s.mu)
+	if err := collect(app, s.components, s.routes); err != nil {
+		return nil, err
+	}
+	for _, c := range s.components {
+		c.inbox = newMailbox()
+		values, ok, err := constants.Parse(c.leaf)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("Component %s: %s", c.path, err))
+		}
+		if ok {
+			c.handler = constantsHandler(values)
+		}
+	}
+	return s, nil
+}
+
+// constants components publish their values on start,
+// unless other handler is attached
+func constantsHandler(c constants.Constants) Handler {
+	return HandlerFuncs{
+		OnStart: func(ctx *Context) error {
+			for _, value := range c.Values {
+				if err := ctx.Publish(value.Pin, value.Value); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+}
+
+// attaches handler to leaf component by its dotted path,
+// components without handler ignore incoming signals
+func (s *Simulator) Handle(path string, h Handler) error {
+	c, ok := s.components[path]
+	if !ok {
+		return errors.New(fmt.Sprintf("Unknown leaf component: %s", path))
+	}
+	c.handler = h
+	return nil
+}
+
+// receives every signal, command call and inject, should be set before Run
+func (s *Simulator) SetTracer(t Tracer) {
+	s.tracer = t
+}
+
+func (s *Simulator) trace(e Event) {
+	if s.tracer == nil {
+		return
+	}
+	e.Time = time.Now()
+	s.tracer.Trace(e)
+}
+
+// delivers value to consume-signal pin of component as if it came by binding,
+// may be called before Run to seed the simulation
+func (s *Simulator) Inject(path string, pin manifest.PinId, value interface{}) error {
+	c, ok := s.components[path]
+	if !ok {
+		return errors.New(fmt.Sprintf("Unknown leaf component: %s", path))
+	}
+	to := endpoint{path, pin}
+	if _, err := c.signalPin(pin, manifest.Receives); err != nil {
+		return err
+	}
+	if err := s.checkSignal(to, value); err != nil {
+		return err
+	}
+	s.trace(Event{Kind: InjectEvent, Component: path, Pin: pin, Payload: value})
+	s.enqueue(message{target: to, value: value})
+	return nil
+}
+
+// starts all handlers and delivers messages until no more are pending,
+// returns first error produced by handler or by type checking
+func (s *Simulator) Run() error {
+	if s.started {
+		return errors.New("Simulation can only be run once")
+	}
+	s.started = true
+	if s.mode == Deterministic {
+		return s.runDeterministic()
+	}
+	return s.runConcurrent()
+}
+
+func (s *Simulator) sortedComponents() []*component {
+	paths := make([]string, 0, len(s.components))
+	for path := range s.components {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	result := make([]*component, len(paths))
+	for i, path := range paths {
+		result[i] = s.components[path]
+	}
+	return result
+}
+
+func (s *Simulator) runDeterministic() error {
+	for _, c := range s.sortedComponents() {
+		if c.handler == nil {
+			continue
+		}
+		if err := c.handler.Start(&Context{s, c}); err != nil {
+			return err
+		}
+		if s.err != nil {
+			return s.err
+		}
+	}
+	for len(s.queue) > 0 {
+		m := s.queue[0]
+		s.queue = s.queue[1:]
+		c := s.components[m.target.Component]
+		if m.reply != nil {
+			result, err := s.invoke(c, m.target.Pin, m.value.(map[string]interface{}))
+			m.reply <- reply{result, err}
+			continue
+		}
+		if c.handler == nil {
+			continue
+		}
+		if err := c.handler.Signal(&Context{s, c}, m.target.Pin, m.value); err != nil {
+			return err
+		}
+		if s.err != nil {
+			return s.err
+		}
+	}
+	return nil
+}
+
+func (s *Simulator) runConcurrent() error {
+	var wg sync.WaitGroup
+	components := s.sortedComponents()
+	s.mu.Lock()
+	s.pending += len(components)
+	s.mu.Unlock()
+	for _, c := range components {
+		wg.Add(1)
+		go func(c *component) {
+			defer wg.Done()
+			s.loop(c)
+		}(c)
+	}
+	s.mu.Lock()
+	for s.pending > 0 && s.err == nil {
+		s.idle.Wait()
+	}
+	err := s.err
+	s.mu.Unlock()
+	for _, c := range components {
+		c.inbox.close()
+	}
+	wg.Wait()
+	return err
+}
+
+// goroutine of a single component
+func (s *Simulator) loop(c *component) {
+	ctx := &Context{s, c}
+	if c.handler != nil {
+		s.fail(c.handler.Start(ctx))
+	}
+	s.done()
+	for {
+		m, ok := c.inbox.get()
+		if !ok {
+			return
+		}
+		if m.reply != nil {
+			result, err := s.invoke(c, m.target.Pin, m.value.(map[string]interface{}))
+			m.reply <- reply{result, err}
+		} else if c.handler != nil {
+			s.fail(c.handler.Signal(ctx, m.target.Pin, m.value))
+		}
+		s.done()
+	}
+}
+
+func (s *Simulator) enqueue(m message) {
+	if s.mode == Deterministic {
+		s.queue = append(s.queue, m)
+		return
+	}
+	s.mu.Lock()
+	s.pending++
+	s.mu.Unlock()
+	if !s.components[m.target.Component].inbox.put(m) {
+		s.done()
+		if m.reply != nil {
+			m.reply <- reply{nil, errors.New("Simulation is stopped")}
+		}
+	}
+}
+
+func (s *Simulator) done() {
+	s.mu.Lock()
+	s.pending--
+	if s.pending == 0 {
+		s.idle.Broadcast()
+	}
+	s.mu.Unlock()
+}
+
+// records first error and wakes up Run
+func (s *Simulator) fail(err error) {
+	if err == nil {
+		return
+	}
+	s.mu.Lock()
+	if s.err == nil {
+		s.err = err
+	}
+	s.idle.Broadcast()
+	s.mu.Unlock()
+}
+
+func (s *Simulator) checkSignal(to endpoint, value interface{}) error {
+	pin, err := s.components[to.Component].signalPin(to.Pin, manifest.Receives)
+	if err != nil {
+		return err
+	}
+	if err := datatype.Check(pin.DataType, value); err != nil {
+		return errors.New(fmt.Sprintf("%s: %s", to, err))
+	}
+	return nil
+}
+
+func (s *Simulator) publish(c *component, pin manifest.PinId, value interface{}) error {
+	from := endpoint{c.path, pin}
+	signal, err := c.signalPin(pin, manifest.Sends)
+	if err == nil {
+		err = datatype.Check(signal.DataType, value)
+		if err != nil {
+			err = errors.New(fmt.Sprintf("%s: %s", from, err))
+		}
+	}
+	for _, to := range s.routes[from] {
+		if err != nil {
+			break
+		}
+		err = s.checkSignal(to, value)
+	}
+	if err != nil {
+		s.fail(err)
+		return err
+	}
+	// traced before delivery, so reactions of consumers come after it
+	s.trace(Event{Kind: SignalEvent, Component: c.path, Pin: pin, Payload: value})
+	for _, to := range s.routes[from] {
+		s.enqueue(message{target: to, value: value})
+	}
+	return nil
+}
+
+func (s *Simulator) call(c *component, pin manifest.PinId, args map[string]interface{}) (map[string]interface{}, error) {
+	from := endpoint{c.path, pin}
+	command, err := c.commandPin(pin, manifest.Sends)
+	if err != nil {
+		return nil, err
+	}
+	if err := datatype.Check(command.Arguments, args); err != nil {
+		return nil, errors.New(fmt.Sprintf("%s: %s", from, err))
+	}
+	targets := s.routes[from]
+	if len(targets) != 1 {
+		return nil, errors.New(fmt.Sprintf("%s: expected exactly one receiver, got %d", from, len(targets)))
+	}
+	to := targets[0]
+	target := s.components[to.Component]
+	// call and its result are separate events, so nested calls
+	// of receiver are traced between them
+	s.trace(Event{Kind: CommandEvent, Component: c.path, Pin: pin, Payload: args})
+	var r reply
+	if target.stub {
+		r = s.recordedCall(from, command)
+	} else if s.mode == Deterministic {
+		r.result, r.err = s.invoke(target, to.Pin, args)
+	} else {
+		replies := make(chan reply, 1)
+		s.enqueue(message{target: to, value: args, reply: replies})
+		r = <-replies
+	}
+	e := Event{Kind: ResultEvent, Component: c.path, Pin: pin, Result: r.result}
+	if r.err != nil {
+		e.Error = r.err.Error()
+	}
+	s.trace(e)
+	return r.result, r.err
+}
+
+// runs receive-command handler with type checking of arguments and result
+func (s *Simulator) invoke(c *component, pin manifest.PinId, args map[string]interface{}) (map[string]interface{}, error) {
+	to := endpoint{c.path, pin}
+	command, err := c.commandPin(pin, manifest.Receives)
+	if err != nil {
+		return nil, err
+	}
+	if err := datatype.Check(command.Arguments, args); err != nil {
+		return nil, errors.New(fmt.Sprintf("%s: %s", to, err))
+	}
+	if c.handler == nil {
+		return nil, errors.New(fmt.Sprintf("%s: component has no handler", to))
+	}
+	result, err := c.handler.Command(&Context{s, c}, pin, args)
+	if err != nil {
+		return nil, err
+	}
+	if err := datatype.Check(command.Result, result); err != nil {
+		return nil, errors.New(fmt.Sprintf("%s: result: %s", to, err))
+	}
+	return result, nil
+}
+
+func (c *component) pin(id manifest.PinId, direction manifest.Direction) (manifest.DirectedPinType, error) {
+	pin, ok := c.leaf.Interfaces[id.Interface].Pins[id.Pin]
+	if !ok {
+		return pin, errors.New(fmt.Sprintf("Unknown pin: %s", endpoint{c.path, id}))
+	}
+	if pin.Direction != direction {
+		return pin, errors.New(fmt.Sprintf("Pin %s has wrong direction", endpoint{c.path, id}))
+	}
+	return pin, nil
+}
+
+func (c *component) signalPin(id manifest.PinId, direction manifest.Direction) (manifest.SignalPin, error) {
+	pin, err := c.pin(id, direction)
+	if err != nil {
+		return manifest.SignalPin{}, err
+	}
+	signal, ok := pin.PinType.(manifest.SignalPin)
+	if !ok {
+		return signal, errors.New(fmt.Sprintf("Pin %s is not a signal", endpoint{c.path, id}))
+	}
+	return signal, nil
+}
+
+func (c *component) commandPin(id manifest.PinId, direction manifest.Direction) (manifest.CommandPin, error) {
+	pin, err := c.pin(id, direction)
+	if err != nil {
+		return manifest.CommandPin{}, err
+	}
+	command, ok := pin.PinType.(manifest.CommandPin)
+	if !ok {
+		return command, errors.New(fmt.Sprintf("Pin %s is not a command", endpoint{c.path, id}))
+	}
+	return command, nil
+}
+
+// handle given to handlers to interact with the rest of application
+type Context struct {
+	sim       *Simulator
+	component *component
+}
+
+func (c *Context) Component() manifest.ComponentId {
+	return c.component.id
+}
+
+// sends value from publish-signal pin to all bound consumers
+func (c *Context) Publish(pin manifest.PinId, value interface{}) error {
+	return c.sim.publish(c.component, pin, value)
+}
+
+// invokes the receiver bound to send-command pin and waits for result;
+// in concurrent mode calls must not form a cycle between components
+func (c *Context) Call(pin manifest.PinId, args map[string]interface{}) (map[string]interface{}, error) {
+	return c.sim.call(c.component, pin, args)
+}
+
+// unbounded FIFO queue, so publishing never blocks the publisher
+type mailbox struct {
+	mu       sync.Mutex
+	nonEmpty *sync.Cond
+	messages []message
+	closed   bool
+}
+
+func newMailbox() *mailbox {
+	m := &mailbox{}
+	m.nonEmpty = sync.NewCond(&m.mu)
+	return m
+}
+
+func (m *mailbox) put(msg message) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return false
+	}
+	m.messages = append(m.messages, msg)
+	m.nonEmpty.Signal()
+	return true
+}
+
+// blocks until message arrives, returns false once mailbox is closed
+func (m *mailbox) get() (message, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for len(m.messages) == 0 && !m.closed {
+		m.nonEmpty.Wait()
+	}
+	if m.closed {
+		return message{}, false
+	}
+	msg := m.messages[0]
+	m.messages = m.messages[1:]
+	return msg, true
+}
+
+// drops undelivered messages, failing pending command calls
+func (m *mailbox) close() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.closed = true
+	for _, msg := range m.messages {
+		if msg.reply != nil {
+			msg.reply <- reply{nil, errors.New("Simulation is stopped")}
+		}
+	}
+	m.messages = nil
+	m.nonEmpty.Broadcast()
+}
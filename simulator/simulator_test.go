@@ -0,0 +1,205 @@
+package simulator
+
+import (
+	"github.com/chemikadze/gonomi/manifest"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+const pipeline = `
+    application:
+        components:
+            source:
+                type: test.Source
+                interfaces:
+                    out:
+                        value: publish-signal(int)
+                        double: send-command(int x)
+            sink:
+                type: test.Sink
+                interfaces:
+                    out:
+                        value: consume-signal(int)
+                        double: receive-command(int x)
+        bindings:
+            - [source, sink]
+`
+
+func parse(t *testing.T, m string) manifest.Application {
+	app, err := manifest.Parse(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return app
+}
+
+func runPipeline(t *testing.T, mode Mode, values ...interface{}) ([]interface{}, error) {
+	sim, err := New(parse(t, pipeline), mode)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var mu sync.Mutex
+	received := []interface{}{}
+	sim.Handle("source", HandlerFuncs{OnStart: func(c *Context) error {
+		for _, v := range values {
+			if err := c.Publish(manifest.PinId{"out", "value"}, v); err != nil {
+				return err
+			}
+		}
+		return nil
+	}})
+	sim.Handle("sink", HandlerFuncs{OnSignal: func(c *Context, pin manifest.PinId, value interface{}) error {
+		mu.Lock()
+		defer mu.Unlock()
+		received = append(received, value)
+		return nil
+	}})
+	err = sim.Run()
+	return received, err
+}
+
+func TestSignalDeterministic(t *testing.T) {
+	received, err := runPipeline(t, Deterministic, 1, 2, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(received, []interface{}{1, 2, 3}) {
+		t.Error("Unexpected signals:", received)
+	}
+}
+
+func TestSignalConcurrent(t *testing.T) {
+	received, err := runPipeline(t, Concurrent, 1, 2, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(received, []interface{}{1, 2, 3}) {
+		t.Error("Unexpected signals:", received)
+	}
+}
+
+func TestSignalTypeMismatch(t *testing.T) {
+	for _, mode := range []Mode{Deterministic, Concurrent} {
+		_, err := runPipeline(t, mode, "1")
+		if err == nil {
+			t.Error("Type mismatch expected")
+		}
+	}
+}
+
+func TestCommand(t *testing.T) {
+	for _, mode := range []Mode{Deterministic, Concurrent} {
+		sim, err := New(parse(t, pipeline), mode)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var result map[string]interface{}
+		sim.Handle("source", HandlerFuncs{OnStart: func(c *Context) error {
+			result, err = c.Call(manifest.PinId{"out", "double"}, map[string]interface{}{"x": 2})
+			return err
+		}})
+		sim.Handle("sink", HandlerFuncs{OnCommand: func(c *Context, pin manifest.PinId, args map[string]interface{}) (map[string]interface{}, error) {
+			if pin != (manifest.PinId{"out", "double"}) {
+				t.Error("Unexpected pin", pin)
+			}
+			return map[string]interface{}{}, nil
+		}})
+		if err := sim.Run(); err != nil {
+			t.Fatal(err)
+		}
+		if result == nil {
+			t.Error("Command result expected")
+		}
+	}
+}
+
+func TestInject(t *testing.T) {
+	sim, err := New(parse(t, pipeline), Deterministic)
+	if err != nil {
+		t.Fatal(err)
+	}
+	received := []interface{}{}
+	sim.Handle("sink", HandlerFuncs{OnSignal: func(c *Context, pin manifest.PinId, value interface{}) error {
+		received = append(received, value)
+		return nil
+	}})
+	if err := sim.Inject("sink", manifest.PinId{"out", "value"}, 42); err != nil {
+		t.Fatal(err)
+	}
+	if err := sim.Inject("sink", manifest.PinId{"out", "value"}, "42"); err == nil {
+		t.Error("Type mismatch expected")
+	}
+	if err := sim.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(received, []interface{}{42}) {
+		t.Error("Unexpected signals:", received)
+	}
+}
+
+func TestUnknownBindingTarget(t *testing.T) {
+	_, err := New(parse(t, `
+        application:
+            components:
+                x:
+                    type: test.Component
+            bindings:
+                - [x, y]
+    `), Deterministic)
+	if err == nil {
+		t.Error("Error expected")
+	}
+}
+
+func TestConstantsSource(t *testing.T) {
+	sim, err := New(parse(t, `
+        application:
+            components:
+                constants:
+                    type: cobalt.common.Constants
+                    configuration:
+                        out.value: 42
+                        out.extra: 1
+                        note: published by nobody
+                    interfaces:
+                        out:
+                            value: publish-signal(int)
+                            unset: publish-signal(string)
+                sink:
+                    type: test.Sink
+                    interfaces:
+                        out:
+                            value: consume-signal(int)
+            bindings:
+                - [constants, sink]
+    `), Deterministic)
+	if err != nil {
+		t.Fatal(err)
+	}
+	received := []interface{}{}
+	sim.Handle("sink", HandlerFuncs{OnSignal: func(c *Context, pin manifest.PinId, value interface{}) error {
+		received = append(received, value)
+		return nil
+	}})
+	if err := sim.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(received, []interface{}{42}) {
+		t.Error("Unexpected signals:", received)
+	}
+	_, err = New(parse(t, `
+        application:
+            components:
+                constants:
+                    type: cobalt.common.Constants
+                    configuration:
+                        out.value: "42"
+                    interfaces:
+                        out:
+                            value: publish-signal(int)
+    `), Deterministic)
+	if err == nil || err.Error() != "Component constants: Constant out.value: Expected int, got string" {
+		t.Error("Unexpected error:", err)
+	}
+}
@@ -0,0 +1,177 @@
+package simulator
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/chemikadze/gonomi/manifest"
+	"io"
+	"sync"
+	"time"
+)
+
+const (
+	// value published by component
+	SignalEvent = "signal"
+	// command called by component
+	CommandEvent = "command"
+	// result or error of command returned to caller
+	ResultEvent = "result"
+	// value delivered to component from outside of simulation
+	InjectEvent = "inject"
+)
+
+// single step of simulation, component and pin are the ones of publisher,
+// caller or inject target
+type Event struct {
+	Time      time.Time              `json:"time"`
+	Kind      string                 `json:"kind"`
+	Component string                 `json:"component"`
+	Pin       manifest.PinId         `json:"pin"`
+	Payload   interface{}            `json:"payload"`
+	Result    map[string]interface{} `json:"result,omitempty"`
+	Error     string                 `json:"error,omitempty"`
+}
+
+type Tracer interface {
+	Trace(e Event)
+}
+
+// writes events as JSON Lines, safe for concurrent use
+type TraceWriter struct {
+	mu  sync.Mutex
+	w   io.Writer
+	err error
+}
+
+func NewTraceWriter(w io.Writer) *TraceWriter {
+	return &TraceWriter{w: w}
+}
+
+func (t *TraceWriter) Trace(e Event) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.err != nil {
+		return
+	}
+	line, err := encodeEvent(e)
+	if err == nil {
+		_, err = t.w.Write(append(line, '\n'))
+	}
+	t.err = err
+}
+
+// first error occurred while writing trace
+func (t *TraceWriter) Err() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.err
+}
+
+// collects events in memory, safe for concurrent use
+type TraceBuffer struct {
+	mu     sync.Mutex
+	events []Event
+}
+
+func (t *TraceBuffer) Trace(e Event) {
+	t.mu.Lock()
+	t.events = append(t.events, e)
+	t.mu.Unlock()
+}
+
+func (t *TraceBuffer) Events() []Event {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]Event{}, t.events...)
+}
+
+func ReadTrace(r io.Reader) ([]Event, error) {
+	events := []Event{}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 16*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		decoder := json.NewDecoder(bytes.NewReader(scanner.Bytes()))
+		decoder.UseNumber()
+		e := Event{}
+		if err := decoder.Decode(&e); err != nil {
+			return nil, errors.New(fmt.Sprintf("line %d: %s", line, err))
+		}
+		events = append(events, e)
+	}
+	return events, scanner.Err()
+}
+
+func encodeEvent(e Event) ([]byte, error) {
+	// yaml decoder produces maps with interface{} keys which json can't encode
+	e.Payload = manifest.JSONValue(e.Payload)
+	if e.Result != nil {
+		e.Result = manifest.JSONValue(e.Result).(map[string]interface{})
+	}
+	return json.Marshal(e)
+}
+
+// lists differences between traces ignoring timestamps,
+// removed events are prefixed with "-" and added ones with "+"
+func DiffTraces(old, new []Event) ([]string, error) {
+	a, err := eventKeys(old)
+	if err != nil {
+		return nil, err
+	}
+	b, err := eventKeys(new)
+	if err != nil {
+		return nil, err
+	}
+	// longest common subsequence table
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+	diff := []string{}
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			i++
+			j++
+		case j == len(b) || (i < len(a) && lcs[i+1][j] >= lcs[i][j+1]):
+			diff = append(diff, "-"+a[i])
+			i++
+		default:
+			diff = append(diff, "+"+b[j])
+			j++
+		}
+	}
+	return diff, nil
+}
+
+func eventKeys(events []Event) ([]string, error) {
+	keys := make([]string, len(events))
+	for i, e := range events {
+		e.Time = time.Time{}
+		line, err := encodeEvent(e)
+		if err != nil {
+			return nil, err
+		}
+		keys[i] = string(line)
+	}
+	return keys, nil
+}
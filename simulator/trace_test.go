@@ -0,0 +1,204 @@
+package simulator
+
+import (
+	"bytes"
+	"github.com/chemikadze/gonomi/manifest"
+	"reflect"
+	"testing"
+)
+
+func recordPipeline(t *testing.T, values ...interface{}) []Event {
+	sim, err := New(parse(t, pipeline), Deterministic)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	writer := NewTraceWriter(&out)
+	sim.SetTracer(writer)
+	sim.Handle("source", HandlerFuncs{OnStart: func(c *Context) error {
+		for _, v := range values {
+			if err := c.Publish(manifest.PinId{"out", "value"}, v); err != nil {
+				return err
+			}
+		}
+		_, err := c.Call(manifest.PinId{"out", "double"}, map[string]interface{}{"x": 1})
+		return err
+	}})
+	sim.Handle("sink", HandlerFuncs{OnCommand: func(c *Context, pin manifest.PinId, args map[string]interface{}) (map[string]interface{}, error) {
+		return map[string]interface{}{}, nil
+	}})
+	if err := sim.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if writer.Err() != nil {
+		t.Fatal(writer.Err())
+	}
+	events, err := ReadTrace(&out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return events
+}
+
+func TestTraceRecording(t *testing.T) {
+	events := recordPipeline(t, 1, 2)
+	if len(events) != 4 {
+		t.Fatal("Expected 4 events, got", events)
+	}
+	expected := []string{SignalEvent, SignalEvent, CommandEvent, ResultEvent}
+	for i, e := range events {
+		if e.Kind != expected[i] || e.Component != "source" || e.Time.IsZero() {
+			t.Error("Unexpected event", e)
+		}
+	}
+}
+
+const chain = `
+    application:
+        components:
+            a:
+                type: test.A
+                interfaces:
+                    out:
+                        value: publish-signal(int)
+                        double: send-command(int x => int y)
+            b:
+                type: test.B
+                interfaces:
+                    in:
+                        value: consume-signal(int)
+                        double: receive-command(int x => int y)
+                    out:
+                        value: publish-signal(int)
+                        double: send-command(int x => int y)
+            c:
+                type: test.C
+                interfaces:
+                    in:
+                        value: consume-signal(int)
+                        double: receive-command(int x => int y)
+        bindings:
+            - [a#out, b#in]
+            - [b#out, c#in]
+`
+
+// reactions and nested calls are traced after events causing them
+func TestTraceCausalOrder(t *testing.T) {
+	for _, mode := range []Mode{Deterministic, Concurrent} {
+		sim, err := New(parse(t, chain), mode)
+		if err != nil {
+			t.Fatal(err)
+		}
+		trace := &TraceBuffer{}
+		sim.SetTracer(trace)
+		sim.Handle("a", HandlerFuncs{OnStart: func(c *Context) error {
+			if err := c.Publish(manifest.PinId{"out", "value"}, 1); err != nil {
+				return err
+			}
+			_, err := c.Call(manifest.PinId{"out", "double"}, map[string]interface{}{"x": 1})
+			return err
+		}})
+		sim.Handle("b", HandlerFuncs{
+			OnSignal: func(c *Context, pin manifest.PinId, value interface{}) error {
+				return c.Publish(manifest.PinId{"out", "value"}, value)
+			},
+			OnCommand: func(c *Context, pin manifest.PinId, args map[string]interface{}) (map[string]interface{}, error) {
+				return c.Call(manifest.PinId{"out", "double"}, args)
+			},
+		})
+		sim.Handle("c", HandlerFuncs{OnCommand: func(c *Context, pin manifest.PinId, args map[string]interface{}) (map[string]interface{}, error) {
+			return map[string]interface{}{"y": 2 * args["x"].(int)}, nil
+		}})
+		if err := sim.Run(); err != nil {
+			t.Fatal(err)
+		}
+		index := map[string]int{}
+		for i, e := range trace.Events() {
+			index[e.Kind+" "+e.Component] = i
+		}
+		for _, order := range [][2]string{
+			{"signal a", "signal b"},
+			{"command a", "command b"},
+			{"command b", "result b"},
+			{"result b", "result a"},
+		} {
+			first, ok1 := index[order[0]]
+			second, ok2 := index[order[1]]
+			if !ok1 || !ok2 || first > second {
+				t.Errorf("Mode %d: %s expected before %s in %v", mode, order[0], order[1], trace.Events())
+			}
+		}
+	}
+}
+
+func TestTraceDiff(t *testing.T) {
+	diff, err := DiffTraces(recordPipeline(t, 1, 2), recordPipeline(t, 1, 2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diff) != 0 {
+		t.Error("Traces of the same run should not differ:", diff)
+	}
+	diff, err = DiffTraces(recordPipeline(t, 1, 2), recordPipeline(t, 1, 3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diff) != 2 || diff[0][0] != '-' || diff[1][0] != '+' {
+		t.Error("Expected one changed event, got:", diff)
+	}
+}
+
+func TestReplay(t *testing.T) {
+	events := recordPipeline(t, 1, 2)
+	sim, err := New(parse(t, pipeline), Deterministic)
+	if err != nil {
+		t.Fatal(err)
+	}
+	received := []interface{}{}
+	sim.Handle("sink", HandlerFuncs{
+		OnSignal: func(c *Context, pin manifest.PinId, value interface{}) error {
+			received = append(received, value)
+			return nil
+		},
+		OnCommand: func(c *Context, pin manifest.PinId, args map[string]interface{}) (map[string]interface{}, error) {
+			received = append(received, args)
+			return nil, nil
+		},
+	})
+	if err := sim.Replay(events, "sink"); err != nil {
+		t.Fatal(err)
+	}
+	if err := sim.Run(); err != nil {
+		t.Fatal(err)
+	}
+	expected := []interface{}{1, 2, map[string]interface{}{"x": 1}}
+	if !reflect.DeepEqual(received, expected) {
+		t.Errorf("\nReplayed: %v\nExpect:   %v", received, expected)
+	}
+}
+
+func TestReplayStubbedCommand(t *testing.T) {
+	events := recordPipeline(t)
+	sim, err := New(parse(t, pipeline), Deterministic)
+	if err != nil {
+		t.Fatal(err)
+	}
+	calls := 0
+	sim.Handle("source", HandlerFuncs{OnStart: func(c *Context) error {
+		for i := 0; i < 2; i++ {
+			if _, err := c.Call(manifest.PinId{"out", "double"}, map[string]interface{}{"x": 1}); err == nil {
+				calls++
+			}
+		}
+		return nil
+	}})
+	if err := sim.Replay(events, "source"); err != nil {
+		t.Fatal(err)
+	}
+	if err := sim.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 1 {
+		t.Error("Only recorded call should succeed, got", calls)
+	}
+}
@@ -0,0 +1,21 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	if diff := unifiedDiff("a", "b", "x\ny\n", "x\ny\n"); diff != "" {
+		t.Error("Equal texts should have empty diff, got", diff)
+	}
+	diff := unifiedDiff("a", "b", "1\n2\n3\n4\n5\n6\n7\n8\n9\n", "1\n2\n3\n4\nfive\n6\n7\n8\n9\n")
+	expected := "--- a\n+++ b\n@@ -2,7 +2,7 @@\n 2\n 3\n 4\n-5\n+five\n 6\n 7\n 8\n"
+	if diff != expected {
+		t.Errorf("\nDiff:\n%s\nExpect:\n%s", diff, expected)
+	}
+	diff = unifiedDiff("a", "b", "", "x\n")
+	expected = "--- a\n+++ b\n@@ -0,0 +1,1 @@\n+x\n"
+	if diff != expected {
+		t.Errorf("\nDiff:\n%s\nExpect:\n%s", diff, expected)
+	}
+}
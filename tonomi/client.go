@@ -0,0 +1,291 @@
+// Package tonomi is a client of tonomi.com REST API: applications and
+// their manifests, instances, workflows and signals.
+//
+//	c := tonomi.New("https://tonomi.com", token)
+//	app, err := c.CreateApplication("my-org", "web")
+//	_, err = c.UploadManifest(app.Id, manifest)
+//	instance, err := c.Launch(app.Id, tonomi.LaunchRequest{Name: "web-1"})
+//
+// package tonomitest serves the same endpoints in-process for tests.
+package tonomi
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// instance is being launched or runs a workflow
+	Executing = "Executing"
+	Running   = "Running"
+	Failed    = "Failed"
+	// destroy workflow runs
+	Destroying = "Destroying"
+	Destroyed  = "Destroyed"
+)
+
+type Application struct {
+	Id   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type Instance struct {
+	Id            string `json:"id"`
+	Name          string `json:"name"`
+	ApplicationId string `json:"applicationId"`
+	Status        string `json:"status"`
+	// workflow instance runs or ran last
+	Workflow string `json:"workflow,omitempty"`
+}
+
+type LaunchRequest struct {
+	Name string `json:"instanceName,omitempty"`
+	// values of configuration pins of application
+	Parameters map[string]interface{} `json:"parameters,omitempty"`
+	// launches sharing key start one instance, generated when empty
+	IdempotencyKey string `json:"-"`
+}
+
+// manifest version created by upload
+type ManifestVersion struct {
+	ApplicationId string `json:"applicationId"`
+	Version       int    `json:"version"`
+}
+
+// response with status other than 2xx
+type APIError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e APIError) Error() string {
+	return fmt.Sprintf("HTTP %d: %s", e.StatusCode, e.Message)
+}
+
+type Client struct {
+	// e.g. https://tonomi.com, API paths are appended to it
+	BaseURL    string
+	Token      string
+	HTTPClient *http.Client
+	Retry      RetryPolicy
+	// called before every retry, e.g. for logging
+	OnRetry func(Retry)
+	// replaced by tests
+	sleep   func(time.Duration)
+	metrics metrics
+}
+
+func New(baseURL, token string) *Client {
+	return &Client{
+		BaseURL:    strings.TrimSuffix(baseURL, "/"),
+		Token:      token,
+		HTTPClient: http.DefaultClient,
+		Retry:      DefaultRetryPolicy,
+		sleep:      time.Sleep,
+	}
+}
+
+func (c *Client) Applications(organization string) ([]Application, error) {
+	result := []Application{}
+	err := c.do("GET", "/api/1/organizations/"+url.PathEscape(organization)+"/applications", nil, &result)
+	return result, err
+}
+
+func (c *Client) CreateApplication(organization, name string) (Application, error) {
+	result := Application{}
+	err := c.do("POST", "/api/1/organizations/"+url.PathEscape(organization)+"/applications", Application{Name: name}, &result)
+	return result, err
+}
+
+// latest uploaded manifest of application
+func (c *Client) Manifest(applicationId string) (string, error) {
+	var result rawBody
+	err := c.do("GET", applicationPath(applicationId)+"/manifest", nil, &result)
+	return string(result), err
+}
+
+func (c *Client) UploadManifest(applicationId, manifest string) (ManifestVersion, error) {
+	result := ManifestVersion{}
+	err := c.send("POST", applicationPath(applicationId)+"/manifest", NewIdempotencyKey(), rawBody(manifest), &result)
+	return result, err
+}
+
+// starts instance of latest manifest of application
+func (c *Client) Launch(applicationId string, request LaunchRequest) (Instance, error) {
+	key := request.IdempotencyKey
+	if key == "" {
+		key = NewIdempotencyKey()
+	}
+	result := Instance{}
+	err := c.send("POST", applicationPath(applicationId)+"/launch", key, request, &result)
+	return result, err
+}
+
+func (c *Client) Instance(id string) (Instance, error) {
+	result := Instance{}
+	err := c.do("GET", instancePath(id), nil, &result)
+	return result, err
+}
+
+// starts workflow of running instance
+func (c *Client) RunWorkflow(instanceId, workflow string, parameters map[string]interface{}) (Instance, error) {
+	if parameters == nil {
+		parameters = map[string]interface{}{}
+	}
+	result := Instance{}
+	err := c.do("POST", instancePath(instanceId)+"/workflows/"+url.PathEscape(workflow), parameters, &result)
+	return result, err
+}
+
+func (c *Client) Destroy(instanceId string) (Instance, error) {
+	result := Instance{}
+	err := c.do("DELETE", instancePath(instanceId), nil, &result)
+	return result, err
+}
+
+// values of signals published by instance, keyed by interface.pin
+func (c *Client) Signals(instanceId string) (map[string]interface{}, error) {
+	result := map[string]interface{}{}
+	err := c.do("GET", instancePath(instanceId)+"/signals", nil, &result)
+	return result, err
+}
+
+// sets value of consume-signal pin of instance, name is interface.pin
+func (c *Client) SendSignal(instanceId, name string, value interface{}) error {
+	return c.do("PUT", instancePath(instanceId)+"/signals/"+url.PathEscape(name), value, nil)
+}
+
+func applicationPath(id string) string {
+	return "/api/1/applications/" + url.PathEscape(id)
+}
+
+func instancePath(id string) string {
+	return "/api/1/instances/" + url.PathEscape(id)
+}
+
+// manifest text, sent and received as is instead of JSON
+type rawBody string
+
+func (c *Client) do(method, path string, body, result interface{}) error {
+	return c.send(method, path, "", body, result)
+}
+
+// sends body encoded as JSON and decodes response into result unless it is nil,
+// failed attempts are retried according to c.Retry
+func (c *Client) send(method, path, idempotencyKey string, body, result interface{}) error {
+	var payload []byte
+	contentType := "application/json"
+	if raw, ok := body.(rawBody); ok {
+		payload, contentType = []byte(raw), "application/x-yaml"
+	} else if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		payload = encoded
+	}
+	for attempt := 1; ; attempt++ {
+		response, data, err := c.attempt(method, path, idempotencyKey, contentType, payload)
+		status := 0
+		if err == nil {
+			status = response.StatusCode
+			if status/100 != 2 {
+				err = APIError{status, errorMessage(data, response.Status)}
+			}
+		}
+		if status == http.StatusTooManyRequests {
+			atomic.AddInt64(&c.metrics.rateLimited, 1)
+		}
+		if err == nil {
+			return decodeResult(method, path, data, result)
+		}
+		if attempt >= c.Retry.MaxAttempts || !retryable(method, idempotencyKey != "", status) {
+			atomic.AddInt64(&c.metrics.failures, 1)
+			return err
+		}
+		delay, ok := c.Retry.delay(attempt, response)
+		if !ok {
+			atomic.AddInt64(&c.metrics.failures, 1)
+			return err
+		}
+		atomic.AddInt64(&c.metrics.retries, 1)
+		if c.OnRetry != nil {
+			c.OnRetry(Retry{method, path, attempt, status, err, delay})
+		}
+		if c.sleep != nil {
+			c.sleep(delay)
+		} else {
+			time.Sleep(delay)
+		}
+	}
+}
+
+// response and its body, response is nil on network errors
+func (c *Client) attempt(method, path, idempotencyKey, contentType string, payload []byte) (*http.Response, []byte, error) {
+	var reader io.Reader
+	if payload != nil {
+		reader = bytes.NewReader(payload)
+	}
+	request, err := http.NewRequest(method, c.BaseURL+path, reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	if payload != nil {
+		request.Header.Set("Content-Type", contentType)
+	}
+	if c.Token != "" {
+		request.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+	if idempotencyKey != "" {
+		request.Header.Set("Idempotency-Key", idempotencyKey)
+	}
+	atomic.AddInt64(&c.metrics.requests, 1)
+	response, err := c.HTTPClient.Do(request)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer response.Body.Close()
+	data, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+	return response, data, nil
+}
+
+func decodeResult(method, path string, data []byte, result interface{}) error {
+	switch result := result.(type) {
+	case nil:
+		return nil
+	case *rawBody:
+		*result = rawBody(data)
+		return nil
+	default:
+		if err := json.Unmarshal(data, result); err != nil {
+			return errors.New(fmt.Sprintf("%s %s: %s", method, path, err))
+		}
+		return nil
+	}
+}
+
+// "message" of JSON error body, body itself or status if it is empty
+func errorMessage(data []byte, status string) string {
+	var body struct {
+		Message string `json:"message"`
+	}
+	if err := json.Unmarshal(data, &body); err == nil && body.Message != "" {
+		return body.Message
+	}
+	if text := strings.TrimSpace(string(data)); text != "" {
+		return text
+	}
+	return status
+}
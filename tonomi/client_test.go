@@ -0,0 +1,81 @@
+package tonomi
+
+import (
+	"github.com/chemikadze/gonomi/tonomi/tonomitest"
+	"reflect"
+	"testing"
+)
+
+const manifest = `application:
+    components:
+        web:
+            type: test.Web
+`
+
+func TestLifecycle(t *testing.T) {
+	s := tonomitest.NewServer()
+	defer s.Close()
+	s.Token = "secret"
+	c := New(s.URL+"/", "secret")
+	app, err := c.CreateApplication("org", "web")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if apps, err := c.Applications("org"); err != nil || !reflect.DeepEqual(apps, []Application{app}) {
+		t.Errorf("Unexpected applications: %v %v", apps, err)
+	}
+	version, err := c.UploadManifest(app.Id, manifest)
+	if err != nil || version != (ManifestVersion{app.Id, 1}) {
+		t.Fatalf("Unexpected version: %v %v", version, err)
+	}
+	if text, err := c.Manifest(app.Id); err != nil || text != manifest {
+		t.Errorf("Unexpected manifest: %q %v", text, err)
+	}
+	instance, err := c.Launch(app.Id, LaunchRequest{Name: "web-1", Parameters: map[string]interface{}{"in.port": 80}})
+	if err != nil || instance.Status != Executing || instance.Name != "web-1" {
+		t.Fatalf("Unexpected instance: %v %v", instance, err)
+	}
+	if parameters := s.Parameters(instance.Id); !reflect.DeepEqual(parameters, map[string]interface{}{"in.port": 80.0}) {
+		t.Errorf("Unexpected parameters: %v", parameters)
+	}
+	if instance, err = c.Instance(instance.Id); err != nil || instance.Status != Running {
+		t.Fatalf("Unexpected instance: %v %v", instance, err)
+	}
+	if instance, err = c.RunWorkflow(instance.Id, "restart", nil); err != nil || instance.Status != Executing || instance.Workflow != "restart" {
+		t.Errorf("Unexpected instance: %v %v", instance, err)
+	}
+	s.SetSignal(instance.Id, "out.url", "http://web")
+	if err := c.SendSignal(instance.Id, "in.replicas", 3); err != nil {
+		t.Error(err)
+	}
+	expected := map[string]interface{}{"out.url": "http://web", "in.replicas": 3.0}
+	if signals, err := c.Signals(instance.Id); err != nil || !reflect.DeepEqual(signals, expected) {
+		t.Errorf("Unexpected signals: %v %v", signals, err)
+	}
+	if instance, err = c.Destroy(instance.Id); err != nil || instance.Status != Destroying {
+		t.Errorf("Unexpected instance: %v %v", instance, err)
+	}
+	if instance, err = c.Instance(instance.Id); err != nil || instance.Status != Destroyed {
+		t.Errorf("Unexpected instance: %v %v", instance, err)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	s := tonomitest.NewServer()
+	defer s.Close()
+	s.Token = "secret"
+	c := New(s.URL, "secret")
+	app, err := c.CreateApplication("org", "web")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Launch(app.Id, LaunchRequest{}); err != (APIError{409, "No manifest uploaded"}) {
+		t.Error("Unexpected error:", err)
+	}
+	if _, err := c.Instance("instance-9"); err == nil || err.Error() != "HTTP 404: No instance instance-9" {
+		t.Error("Unexpected error:", err)
+	}
+	if _, err := New(s.URL, "wrong").Applications("org"); err != (APIError{401, "Invalid token"}) {
+		t.Error("Unexpected error:", err)
+	}
+}
@@ -0,0 +1,126 @@
+package tonomi
+
+import (
+	crand "crypto/rand"
+	"encoding/hex"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// how client retries requests failed with 429, 5xx or network error;
+// POST requests without idempotency key are retried on 429 only, as
+// other failures may come after server has acted on them
+type RetryPolicy struct {
+	// attempts including the first one, 1 disables retries
+	MaxAttempts int
+	// backoff before n-th retry is MinBackoff*2^(n-1) capped by MaxBackoff,
+	// actual delay is random within its upper half
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	// longer Retry-After fails request instead of waiting, 0 waits any time
+	MaxRetryAfter time.Duration
+}
+
+var DefaultRetryPolicy = RetryPolicy{4, 200 * time.Millisecond, 10 * time.Second, time.Minute}
+
+// failed attempt which is about to be retried, passed to Client.OnRetry
+type Retry struct {
+	Method string
+	Path   string
+	// failed attempt, starting from 1
+	Attempt int
+	// 0 for network errors
+	StatusCode int
+	Err        error
+	// wait before next attempt
+	Delay time.Duration
+}
+
+// counters of client since it was created
+type Metrics struct {
+	// attempts sent, including retries
+	Requests int64
+	Retries  int64
+	// 429 responses
+	RateLimited int64
+	// requests failed after last attempt
+	Failures int64
+}
+
+type metrics struct {
+	requests, retries, rateLimited, failures int64
+}
+
+func (c *Client) Metrics() Metrics {
+	return Metrics{
+		atomic.LoadInt64(&c.metrics.requests),
+		atomic.LoadInt64(&c.metrics.retries),
+		atomic.LoadInt64(&c.metrics.rateLimited),
+		atomic.LoadInt64(&c.metrics.failures),
+	}
+}
+
+// random key sent as Idempotency-Key, server acts once on requests sharing it
+func NewIdempotencyKey() string {
+	key := make([]byte, 16)
+	if _, err := crand.Read(key); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 16)
+	}
+	return hex.EncodeToString(key)
+}
+
+// whether failed attempt may be repeated, status is 0 for network errors
+func retryable(method string, idempotent bool, status int) bool {
+	if status == http.StatusTooManyRequests {
+		return true
+	}
+	if method == "POST" && !idempotent {
+		return false
+	}
+	return status == 0 || status/100 == 5
+}
+
+// delay before retry following failed attempt, false when Retry-After
+// of response is longer than policy allows
+func (p RetryPolicy) delay(attempt int, response *http.Response) (time.Duration, bool) {
+	if response != nil {
+		if after, ok := retryAfter(response.Header.Get("Retry-After")); ok {
+			return after, p.MaxRetryAfter == 0 || after <= p.MaxRetryAfter
+		}
+	}
+	return p.backoff(attempt), true
+}
+
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	backoff := p.MinBackoff
+	for i := 1; i < attempt && backoff < p.MaxBackoff; i++ {
+		backoff *= 2
+	}
+	if p.MaxBackoff > 0 && backoff > p.MaxBackoff {
+		backoff = p.MaxBackoff
+	}
+	if backoff <= 0 {
+		return 0
+	}
+	return backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1))
+}
+
+// Retry-After given in seconds or as HTTP date
+func retryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		if after := time.Until(date); after > 0 {
+			return after, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
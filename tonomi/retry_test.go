@@ -0,0 +1,126 @@
+package tonomi
+
+import (
+	"github.com/chemikadze/gonomi/tonomi/tonomitest"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// client of server which records delays instead of sleeping
+func retryingClient(s *tonomitest.Server) (*Client, *[]Retry) {
+	c := New(s.URL, "")
+	retries := &[]Retry{}
+	c.OnRetry = func(r Retry) { *retries = append(*retries, r) }
+	c.sleep = func(time.Duration) {}
+	return c, retries
+}
+
+func TestRetry(t *testing.T) {
+	s := tonomitest.NewServer()
+	defer s.Close()
+	c, retries := retryingClient(s)
+	s.Inject(tonomitest.Fault{Path: "/api/1/organizations/", Status: 503, Times: 2})
+	if _, err := c.Applications("org"); err != nil {
+		t.Fatal(err)
+	}
+	if len(*retries) != 2 || (*retries)[0].Attempt != 1 || (*retries)[1].Attempt != 2 || (*retries)[1].StatusCode != 503 {
+		t.Errorf("Unexpected retries: %v", *retries)
+	}
+	for i, r := range *retries {
+		backoff := c.Retry.MinBackoff << uint(i)
+		if r.Delay < backoff/2 || r.Delay > backoff {
+			t.Errorf("Delay %s of attempt %d is out of [%s, %s]", r.Delay, r.Attempt, backoff/2, backoff)
+		}
+	}
+	if metrics := c.Metrics(); metrics != (Metrics{3, 2, 0, 0}) {
+		t.Errorf("Unexpected metrics: %+v", metrics)
+	}
+	s.Inject(tonomitest.Fault{Status: 500})
+	if _, err := c.Applications("org"); err != (APIError{500, "Injected fault"}) {
+		t.Error("Unexpected error:", err)
+	}
+	if metrics := c.Metrics(); metrics != (Metrics{7, 5, 0, 1}) {
+		t.Errorf("Unexpected metrics: %+v", metrics)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	s := tonomitest.NewServer()
+	defer s.Close()
+	c, retries := retryingClient(s)
+	s.Inject(tonomitest.Fault{Status: 429, RetryAfter: 3 * time.Second, Times: 1})
+	if _, err := c.CreateApplication("org", "web"); err != nil {
+		t.Fatal(err)
+	}
+	if len(*retries) != 1 || (*retries)[0].Delay != 3*time.Second {
+		t.Errorf("Unexpected retries: %v", *retries)
+	}
+	c.Retry.MaxRetryAfter = 2 * time.Second
+	s.Inject(tonomitest.Fault{Status: 429, RetryAfter: 3 * time.Second, Times: 1})
+	if _, err := c.Applications("org"); err != (APIError{429, "Injected fault"}) {
+		t.Error("Unexpected error:", err)
+	}
+	if metrics := c.Metrics(); metrics.RateLimited != 2 || metrics.Failures != 1 {
+		t.Errorf("Unexpected metrics: %+v", metrics)
+	}
+	if after, ok := retryAfter(time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)); !ok || after < 59*time.Minute {
+		t.Errorf("Unexpected Retry-After of date: %s", after)
+	}
+}
+
+// POST without idempotency key may have been acted on, so only 429 is retried
+func TestRetryNonIdempotent(t *testing.T) {
+	s := tonomitest.NewServer()
+	defer s.Close()
+	c, retries := retryingClient(s)
+	s.Inject(tonomitest.Fault{Status: 503, Handled: true, Times: 1})
+	if _, err := c.CreateApplication("org", "web"); err != (APIError{503, "Injected fault"}) {
+		t.Error("Unexpected error:", err)
+	}
+	if len(*retries) != 0 {
+		t.Errorf("Unexpected retries: %v", *retries)
+	}
+}
+
+// launch and upload are retried with the same key, server acts on them once
+func TestIdempotency(t *testing.T) {
+	s := tonomitest.NewServer()
+	defer s.Close()
+	c, retries := retryingClient(s)
+	app, err := c.CreateApplication("org", "web")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Inject(tonomitest.Fault{Method: "POST", Status: 502, Handled: true, Times: 1})
+	version, err := c.UploadManifest(app.Id, manifest)
+	if err != nil || version.Version != 1 {
+		t.Fatalf("Unexpected version: %v %v", version, err)
+	}
+	s.Inject(tonomitest.Fault{Method: "POST", Status: 502, Handled: true, Times: 2})
+	instance, err := c.Launch(app.Id, LaunchRequest{Name: "web-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(*retries) != 3 {
+		t.Errorf("Unexpected retries: %v", *retries)
+	}
+	keys := []string{}
+	for _, r := range s.Requests()[1:] {
+		keys = append(keys, r.Header.Get("Idempotency-Key"))
+	}
+	if len(keys) != 5 || keys[0] == "" || keys[0] != keys[1] || keys[2] == keys[1] || keys[2] != keys[3] || keys[3] != keys[4] {
+		t.Errorf("Unexpected idempotency keys: %q", keys)
+	}
+	if _, err := c.Instance(instance.Id); err != nil {
+		t.Error(err)
+	}
+	again, err := c.Launch(app.Id, LaunchRequest{IdempotencyKey: keys[2], Name: "web-1"})
+	if err != nil || !reflect.DeepEqual(again, instance) {
+		t.Errorf("Launch with used key should return the same instance: %v %v", again, err)
+	}
+	if _, err := c.Instance("instance-3"); err == nil {
+		t.Error("Only one instance expected")
+	}
+}
@@ -0,0 +1,454 @@
+// Package tonomitest serves tonomi.com API in-process for tests: state is
+// kept in memory, instances go through scripted statuses and faults such
+// as latency, server errors or rate limiting are injected on demand.
+//
+//	s := tonomitest.NewServer()
+//	defer s.Close()
+//	s.Script("launch", tonomi.Executing, tonomi.Failed)
+//	s.Inject(tonomitest.Fault{Path: "/api/1/instances/", Status: 503, Times: 1})
+//	c := tonomi.New(s.URL, "token")
+package tonomitest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// statuses of instances as reported by API
+const (
+	executing  = "Executing"
+	running    = "Running"
+	destroying = "Destroying"
+	destroyed  = "Destroyed"
+)
+
+// misbehavior of server for matching requests
+type Fault struct {
+	// method and path prefix of requests fault applies to, empty matches any
+	Method string
+	Path   string
+	// delay before request is handled
+	Latency time.Duration
+	// status returned instead of handling request, 0 handles it
+	Status int
+	// request is handled before Status is returned, as if response was lost
+	Handled bool
+	// Retry-After header of response, rounded up to seconds
+	RetryAfter time.Duration
+	// number of requests fault applies to, 0 for every request
+	Times int
+}
+
+// request as received by server
+type Request struct {
+	Method string
+	Path   string
+	Header http.Header
+}
+
+type Server struct {
+	*httptest.Server
+	// bearer token requests must carry, any token is accepted when empty
+	Token string
+
+	mu           sync.Mutex
+	nextId       int
+	applications map[string]*application
+	// application ids by organization in order of creation
+	organizations map[string][]string
+	instances     map[string]*instance
+	// statuses instance goes through when workflow starts
+	scripts  map[string][]string
+	faults   []*Fault
+	requests []Request
+	// responses of POST requests by path and Idempotency-Key, repeated
+	// requests get them instead of being handled again
+	responses map[string]response
+	// at most limit requests are served in every window
+	limit       int
+	window      time.Duration
+	windowStart time.Time
+	served      int
+}
+
+type response struct {
+	status      int
+	contentType string
+	body        []byte
+}
+
+type application struct {
+	Id        string `json:"id"`
+	Name      string `json:"name"`
+	manifests []string
+}
+
+type instance struct {
+	Id            string `json:"id"`
+	Name          string `json:"name"`
+	ApplicationId string `json:"applicationId"`
+	Status        string `json:"status"`
+	Workflow      string `json:"workflow,omitempty"`
+	// statuses reported by following polls
+	pending    []string
+	parameters map[string]interface{}
+	signals    map[string]interface{}
+}
+
+// started server, Close stops it
+func NewServer() *Server {
+	s := &Server{
+		applications:  map[string]*application{},
+		organizations: map[string][]string{},
+		instances:     map[string]*instance{},
+		responses:     map[string]response{},
+		scripts: map[string][]string{
+			"launch":  {executing, running},
+			"destroy": {destroying, destroyed},
+		},
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	return s
+}
+
+// statuses instance goes through when workflow starts, one per poll
+// of instance; "launch" and "destroy" script launch and destroy, other
+// workflows go through Executing and Running unless scripted
+func (s *Server) Script(workflow string, statuses ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.scripts[workflow] = append([]string{}, statuses...)
+}
+
+// sets status of instance at once, scripted statuses are dropped
+func (s *Server) SetStatus(instanceId, status string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i, ok := s.instances[instanceId]
+	if ok {
+		i.Status, i.pending = status, nil
+	}
+	return ok
+}
+
+// sets value of instance signal, name is interface.pin
+func (s *Server) SetSignal(instanceId, name string, value interface{}) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i, ok := s.instances[instanceId]
+	if ok {
+		i.signals[name] = value
+	}
+	return ok
+}
+
+// signals of instance, published by SetSignal or sent by client
+func (s *Server) Signals(instanceId string) map[string]interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	result := map[string]interface{}{}
+	if i, ok := s.instances[instanceId]; ok {
+		for name, value := range i.signals {
+			result[name] = value
+		}
+	}
+	return result
+}
+
+// parameters instance was launched with
+func (s *Server) Parameters(instanceId string) map[string]interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if i, ok := s.instances[instanceId]; ok {
+		return i.parameters
+	}
+	return nil
+}
+
+// faults apply in order of injection, first matching one wins
+func (s *Server) Inject(f Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = append(s.faults, &f)
+}
+
+func (s *Server) ClearFaults() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = nil
+}
+
+// serves at most requests in every window, others get 429 with
+// Retry-After till end of window; 0 requests disables limit
+func (s *Server) RateLimit(requests int, window time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.limit, s.window, s.windowStart, s.served = requests, window, time.Now(), 0
+}
+
+// requests received so far, including failed by faults
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Request{}, s.requests...)
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.requests = append(s.requests, Request{r.Method, r.URL.Path, r.Header.Clone()})
+	fault := s.fault(r)
+	limited := s.limited()
+	s.mu.Unlock()
+	if fault != nil {
+		time.Sleep(fault.Latency)
+		if fault.Status != 0 && !fault.Handled {
+			s.fail(w, fault.Status, fault.RetryAfter, "Injected fault")
+			return
+		}
+	}
+	if limited > 0 {
+		s.fail(w, http.StatusTooManyRequests, limited, "Rate limit exceeded")
+		return
+	}
+	if s.Token != "" && r.Header.Get("Authorization") != "Bearer "+s.Token {
+		s.fail(w, http.StatusUnauthorized, 0, "Invalid token")
+		return
+	}
+	s.mu.Lock()
+	result := s.handle(r)
+	s.mu.Unlock()
+	if fault != nil && fault.Status != 0 {
+		s.fail(w, fault.Status, fault.RetryAfter, "Injected fault")
+		return
+	}
+	w.Header().Set("Content-Type", result.contentType)
+	w.WriteHeader(result.status)
+	w.Write(result.body)
+}
+
+// response of request, replayed for POST with known Idempotency-Key
+func (s *Server) handle(r *http.Request) response {
+	key := ""
+	if r.Method == "POST" && r.Header.Get("Idempotency-Key") != "" {
+		key = r.URL.Path + " " + r.Header.Get("Idempotency-Key")
+		if result, ok := s.responses[key]; ok {
+			return result
+		}
+	}
+	status, body := s.route(r)
+	result := response{status, "application/json", nil}
+	if status/100 != 2 {
+		result.body, _ = json.Marshal(map[string]string{"message": fmt.Sprint(body)})
+	} else if manifest, ok := body.(string); ok {
+		result.contentType, result.body = "application/x-yaml", []byte(manifest)
+	} else if body != nil {
+		result.body, _ = json.Marshal(body)
+	}
+	if key != "" && status/100 == 2 {
+		s.responses[key] = result
+	}
+	return result
+}
+
+// first fault matching request, its count is used up
+func (s *Server) fault(r *http.Request) *Fault {
+	for i, f := range s.faults {
+		if (f.Method == "" || f.Method == r.Method) && strings.HasPrefix(r.URL.Path, f.Path) {
+			if f.Times > 0 {
+				f.Times--
+				if f.Times == 0 {
+					s.faults = append(s.faults[:i:i], s.faults[i+1:]...)
+				}
+			}
+			return f
+		}
+	}
+	return nil
+}
+
+// time till end of window when request exceeds rate limit, 0 otherwise
+func (s *Server) limited() time.Duration {
+	if s.limit == 0 {
+		return 0
+	}
+	now := time.Now()
+	if now.Sub(s.windowStart) >= s.window {
+		s.windowStart, s.served = now, 0
+	}
+	if s.served < s.limit {
+		s.served++
+		return 0
+	}
+	return s.windowStart.Add(s.window).Sub(now)
+}
+
+func (s *Server) fail(w http.ResponseWriter, status int, retryAfter time.Duration, message string) {
+	if retryAfter > 0 {
+		seconds := (retryAfter + time.Second - 1) / time.Second
+		w.Header().Set("Retry-After", strconv.Itoa(int(seconds)))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"message": message})
+}
+
+// status and body of response, body of error is its message
+func (s *Server) route(r *http.Request) (int, interface{}) {
+	path := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/1/"), "/")
+	for i, segment := range path {
+		path[i], _ = url.PathUnescape(segment)
+	}
+	switch {
+	case len(path) == 3 && path[0] == "organizations" && path[2] == "applications":
+		if r.Method == "POST" {
+			return s.createApplication(r, path[1])
+		}
+		if r.Method == "GET" {
+			return s.listApplications(path[1])
+		}
+	case len(path) == 3 && path[0] == "applications":
+		app, ok := s.applications[path[1]]
+		if !ok {
+			return http.StatusNotFound, "No application " + path[1]
+		}
+		switch {
+		case path[2] == "manifest" && r.Method == "GET":
+			if len(app.manifests) == 0 {
+				return http.StatusNotFound, "No manifest uploaded"
+			}
+			return http.StatusOK, app.manifests[len(app.manifests)-1]
+		case path[2] == "manifest" && r.Method == "POST":
+			return s.uploadManifest(r, app)
+		case path[2] == "launch" && r.Method == "POST":
+			return s.launch(r, app)
+		}
+	case len(path) >= 2 && path[0] == "instances":
+		i, ok := s.instances[path[1]]
+		if !ok {
+			return http.StatusNotFound, "No instance " + path[1]
+		}
+		switch {
+		case len(path) == 2 && r.Method == "GET":
+			if len(i.pending) != 0 {
+				i.Status, i.pending = i.pending[0], i.pending[1:]
+			}
+			return http.StatusOK, i
+		case len(path) == 2 && r.Method == "DELETE":
+			if i.Status == destroying || i.Status == destroyed {
+				return http.StatusOK, i
+			}
+			s.start(i, "destroy")
+			return http.StatusOK, i
+		case len(path) == 4 && path[2] == "workflows" && r.Method == "POST":
+			return s.runWorkflow(r, i, path[3])
+		case len(path) == 3 && path[2] == "signals" && r.Method == "GET":
+			return http.StatusOK, i.signals
+		case len(path) == 4 && path[2] == "signals" && r.Method == "PUT":
+			var value interface{}
+			if err := decode(r, &value); err != nil {
+				return http.StatusBadRequest, err.Error()
+			}
+			i.signals[path[3]] = value
+			return http.StatusNoContent, nil
+		}
+	}
+	return http.StatusNotFound, "No endpoint " + r.Method + " " + r.URL.Path
+}
+
+func (s *Server) id(prefix string) string {
+	s.nextId++
+	return fmt.Sprintf("%s-%d", prefix, s.nextId)
+}
+
+func (s *Server) createApplication(r *http.Request, organization string) (int, interface{}) {
+	var body struct {
+		Name string `json:"name"`
+	}
+	if err := decode(r, &body); err != nil || body.Name == "" {
+		return http.StatusBadRequest, "Application name expected"
+	}
+	app := &application{Id: s.id("app"), Name: body.Name}
+	s.applications[app.Id] = app
+	s.organizations[organization] = append(s.organizations[organization], app.Id)
+	return http.StatusCreated, app
+}
+
+func (s *Server) listApplications(organization string) (int, interface{}) {
+	result := []*application{}
+	for _, id := range s.organizations[organization] {
+		result = append(result, s.applications[id])
+	}
+	return http.StatusOK, result
+}
+
+func (s *Server) uploadManifest(r *http.Request, app *application) (int, interface{}) {
+	data, err := ioutil.ReadAll(r.Body)
+	if err != nil || len(data) == 0 {
+		return http.StatusBadRequest, "Manifest expected"
+	}
+	app.manifests = append(app.manifests, string(data))
+	return http.StatusCreated, map[string]interface{}{"applicationId": app.Id, "version": len(app.manifests)}
+}
+
+func (s *Server) launch(r *http.Request, app *application) (int, interface{}) {
+	if len(app.manifests) == 0 {
+		return http.StatusConflict, "No manifest uploaded"
+	}
+	var body struct {
+		Name       string                 `json:"instanceName"`
+		Parameters map[string]interface{} `json:"parameters"`
+	}
+	if err := decode(r, &body); err != nil {
+		return http.StatusBadRequest, err.Error()
+	}
+	i := &instance{Id: s.id("instance"), Name: body.Name, ApplicationId: app.Id, parameters: body.Parameters, signals: map[string]interface{}{}}
+	if i.Name == "" {
+		i.Name = app.Name
+	}
+	s.instances[i.Id] = i
+	s.start(i, "launch")
+	return http.StatusCreated, i
+}
+
+func (s *Server) runWorkflow(r *http.Request, i *instance, workflow string) (int, interface{}) {
+	if i.Status != running {
+		return http.StatusConflict, "Instance is " + i.Status
+	}
+	var parameters map[string]interface{}
+	if err := decode(r, &parameters); err != nil {
+		return http.StatusBadRequest, err.Error()
+	}
+	s.start(i, workflow)
+	return http.StatusOK, i
+}
+
+// instance reports first status of workflow script, the rest on polls
+func (s *Server) start(i *instance, workflow string) {
+	statuses, ok := s.scripts[workflow]
+	if !ok {
+		statuses = []string{executing, running}
+	}
+	i.Workflow, i.pending = workflow, nil
+	if len(statuses) != 0 {
+		i.Status, i.pending = statuses[0], append([]string{}, statuses[1:]...)
+	}
+}
+
+func decode(r *http.Request, value interface{}) error {
+	data, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return err
+	}
+	if len(data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(data, value)
+}
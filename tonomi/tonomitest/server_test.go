@@ -0,0 +1,98 @@
+package tonomitest
+
+import (
+	"github.com/chemikadze/gonomi/tonomi"
+	"testing"
+	"time"
+)
+
+// client without retries, tests see responses of server as they are
+func client(s *Server) *tonomi.Client {
+	c := tonomi.New(s.URL, "")
+	c.Retry.MaxAttempts = 1
+	return c
+}
+
+func launch(t *testing.T, c *tonomi.Client) tonomi.Instance {
+	app, err := c.CreateApplication("org", "web")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.UploadManifest(app.Id, "application: {}\n"); err != nil {
+		t.Fatal(err)
+	}
+	instance, err := c.Launch(app.Id, tonomi.LaunchRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return instance
+}
+
+func TestScript(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	s.Script("launch", tonomi.Executing, tonomi.Executing, tonomi.Failed)
+	s.Script("backup")
+	c := client(s)
+	instance := launch(t, c)
+	statuses := []string{instance.Status}
+	for i := 0; i < 3; i++ {
+		instance, _ = c.Instance(instance.Id)
+		statuses = append(statuses, instance.Status)
+	}
+	if statuses[0] != tonomi.Executing || statuses[1] != tonomi.Executing || statuses[2] != tonomi.Failed || statuses[3] != tonomi.Failed {
+		t.Errorf("Unexpected statuses: %v", statuses)
+	}
+	if _, err := c.RunWorkflow(instance.Id, "backup", nil); err == nil {
+		t.Error("Workflow of failed instance should be rejected")
+	}
+	s.SetStatus(instance.Id, tonomi.Running)
+	if instance, err := c.RunWorkflow(instance.Id, "backup", nil); err != nil || instance.Status != tonomi.Running {
+		t.Errorf("Empty script should keep status: %v %v", instance, err)
+	}
+}
+
+func TestFaults(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	c := client(s)
+	s.Inject(Fault{Method: "GET", Path: "/api/1/organizations/", Status: 503, Times: 2})
+	s.Inject(Fault{Path: "/api/1/organizations/", Latency: 50 * time.Millisecond, Times: 1})
+	for i := 0; i < 2; i++ {
+		if _, err := c.Applications("org"); err != (tonomi.APIError{503, "Injected fault"}) {
+			t.Error("Unexpected error:", err)
+		}
+	}
+	start := time.Now()
+	if _, err := c.Applications("org"); err != nil {
+		t.Error(err)
+	}
+	if time.Since(start) < 50*time.Millisecond {
+		t.Error("Latency expected")
+	}
+	if _, err := c.Applications("org"); err != nil {
+		t.Error(err)
+	}
+	if requests := s.Requests(); len(requests) != 4 || requests[0].Path != "/api/1/organizations/org/applications" {
+		t.Errorf("Unexpected requests: %v", requests)
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	s.RateLimit(2, time.Minute)
+	c := client(s)
+	for i := 0; i < 2; i++ {
+		if _, err := c.Applications("org"); err != nil {
+			t.Error(err)
+		}
+	}
+	if _, err := c.Applications("org"); err != (tonomi.APIError{429, "Rate limit exceeded"}) {
+		t.Error("Unexpected error:", err)
+	}
+	s.RateLimit(0, 0)
+	if _, err := c.Applications("org"); err != nil {
+		t.Error(err)
+	}
+}